@@ -0,0 +1,401 @@
+package tgbotapp
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"net/http"
+	"strconv"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+var (
+	ErrBroadcastRunning  = errors.New("Broadcast is already running.")
+	ErrBroadcastCanceled = errors.New("Broadcast was canceled.")
+)
+
+// Recipient of a broadcast.
+type Recipient struct {
+	ChatID int64
+	// Position of the recipient in the audience. Once the recipient is
+	// handled, it is stored as the broadcast's cursor.
+	Cursor string
+}
+
+// Audience yields the recipients of a broadcast that follow the recipient
+// with the cursor after, or all of them if after is empty. Recipients must
+// always come in the same order so a broadcast can be resumed.
+type Audience func(ctx context.Context, after string) iter.Seq2[Recipient, error]
+
+// Return an audience of the chats ids.
+func ChatIDs(ids []int64) Audience {
+	return func(ctx context.Context, after string) iter.Seq2[Recipient, error] {
+		return func(yield func(Recipient, error) bool) {
+			start, _ := strconv.Atoi(after)
+			for i := start; i < len(ids); i++ {
+				if !yield(Recipient{ChatID: ids[i], Cursor: strconv.Itoa(i + 1)}, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Build the message sent to chatID. An error stops the broadcast before
+// chatID.
+type MessageFactory func(ctx context.Context, chatID int64) (tgbotapi.Chattable, error)
+
+// State of a broadcast, persisted after each recipient.
+type BroadcastState struct {
+	ID string
+	// Cursor of the last handled recipient.
+	Cursor string
+	Sent   int
+	// Recipients that blocked the bot or left the chat.
+	Blocked int
+	// Recipients whose message was rejected for good, such as for a chat
+	// that does not exist.
+	Failed int
+	Done   bool
+	// Set once the broadcast is canceled. A canceled broadcast never runs
+	// again.
+	Canceled bool
+}
+
+// BroadcastStore persists the state of broadcasts so that they can be resumed
+// after a restart.
+type BroadcastStore interface {
+	// Return the state of broadcast id, or false if there is none.
+	Load(ctx context.Context, id string) (BroadcastState, bool, error)
+	Save(ctx context.Context, state BroadcastState) error
+}
+
+type MemoryBroadcastStore struct {
+	mu     sync.Mutex
+	states map[string]BroadcastState
+}
+
+func NewMemoryBroadcastStore() *MemoryBroadcastStore {
+	return &MemoryBroadcastStore{states: make(map[string]BroadcastState)}
+}
+
+func (s *MemoryBroadcastStore) Load(ctx context.Context, id string) (BroadcastState, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.states[id]
+	return state, ok, nil
+}
+
+func (s *MemoryBroadcastStore) Save(ctx context.Context, state BroadcastState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[state.ID] = state
+	return nil
+}
+
+// Control the broadcast options.
+type BroadcastOption func(*Broadcast)
+
+// Persist the state of the broadcast in store. Defaults to a memory store.
+func WithBroadcastStore(store BroadcastStore) BroadcastOption {
+	return func(b *Broadcast) {
+		b.store = store
+	}
+}
+
+// Call fn with the state of the broadcast after each recipient.
+func WithBroadcastProgress(fn func(BroadcastState)) BroadcastOption {
+	return func(b *Broadcast) {
+		b.onProgress = fn
+	}
+}
+
+// Call fn with the chat id of each recipient that blocked the bot.
+func WithBlockedRecipients(fn func(chatID int64)) BroadcastOption {
+	return func(b *Broadcast) {
+		b.onBlocked = fn
+	}
+}
+
+// Limits of the scheduler used if the application has none. Defaults to
+// DefaultRateLimits.
+func WithBroadcastLimits(limits RateLimits) BroadcastOption {
+	return func(b *Broadcast) {
+		b.limits = limits
+	}
+}
+
+// Broadcast sends a message to every recipient of an audience at bulk
+// priority, through the application's outbound scheduler or one of its own.
+// Recipients that blocked the bot are skipped and reported, and messages
+// rejected with another 4xx error are counted as failed. Any other error,
+// such as a network error, a 5xx error or a flood wait left after retries,
+// stops the broadcast before the recipient so that Run can resume it later.
+type Broadcast struct {
+	app        *Application
+	id         string
+	audience   Audience
+	factory    MessageFactory
+	store      BroadcastStore
+	onProgress func(BroadcastState)
+	onBlocked  func(chatID int64)
+	limits     RateLimits
+
+	mu       sync.Mutex
+	state    BroadcastState
+	running  bool
+	paused   bool
+	resume   chan struct{}
+	cancel   context.CancelFunc
+	canceled bool
+}
+
+// Return new broadcast of the messages built by factory to audience. id
+// identifies the broadcast in its store.
+func NewBroadcast(app *Application, id string, audience Audience, factory MessageFactory, opts ...BroadcastOption) *Broadcast {
+	b := &Broadcast{
+		app:      app,
+		id:       id,
+		audience: audience,
+		factory:  factory,
+		store:    NewMemoryBroadcastStore(),
+		limits:   DefaultRateLimits(),
+		state:    BroadcastState{ID: id},
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return b
+}
+
+// Send the broadcast, resuming after the stored cursor, until all recipients
+// are handled, ctx is done or the broadcast is canceled. Returns the final
+// state.
+func (b *Broadcast) Run(ctx context.Context) (BroadcastState, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	b.mu.Lock()
+	if b.running {
+		b.mu.Unlock()
+		return b.Progress(), ErrBroadcastRunning
+	}
+	b.running = true
+	b.cancel = cancel
+	canceled := b.canceled
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		b.running = false
+		b.cancel = nil
+		b.mu.Unlock()
+	}()
+
+	state, ok, err := b.store.Load(ctx, b.id)
+	if err != nil {
+		return b.Progress(), err
+	}
+	if ok {
+		b.setState(state)
+	}
+	if state.Canceled || canceled {
+		return b.Progress(), b.markCanceled(ctx)
+	}
+	if state.Done {
+		return state, nil
+	}
+
+	scheduler := b.app.Outbound
+	if scheduler == nil {
+		scheduler = NewOutboundScheduler(b.limits)
+		defer scheduler.Close()
+	}
+
+	for r, err := range b.audience(ctx, b.Progress().Cursor) {
+		if err == nil {
+			err = b.wait(ctx)
+		}
+		if err != nil {
+			return b.Progress(), b.stopped(ctx, err)
+		}
+
+		err = b.send(ctx, scheduler, r.ChatID)
+		if ctx.Err() != nil {
+			// The message may not have been sent, keep the cursor.
+			return b.Progress(), b.stopped(ctx, ctx.Err())
+		}
+		if err != nil && !isPermanentError(err) {
+			b.app.Logger.WarnContext(ctx, "Broadcast stopped.", "broadcast", b.id, "chatID", r.ChatID, "error_detail", err)
+			return b.Progress(), b.stopped(ctx, err)
+		}
+
+		if err := b.advance(ctx, r, err); err != nil {
+			return b.Progress(), err
+		}
+	}
+
+	if ctx.Err() != nil {
+		return b.Progress(), b.stopped(ctx, ctx.Err())
+	}
+
+	state = b.Progress()
+	state.Done = true
+	b.setState(state)
+	if err := b.store.Save(ctx, state); err != nil {
+		return state, err
+	}
+	b.report(state)
+
+	return state, nil
+}
+
+func (b *Broadcast) send(ctx context.Context, scheduler *OutboundScheduler, chatID int64) error {
+	msg, err := b.factory(ctx, chatID)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.app.newSender(scheduler).Request(WithPriority(ctx, PriorityBulk), chatID, msg)
+	return err
+}
+
+// Record the result of sending to r and persist the new state.
+func (b *Broadcast) advance(ctx context.Context, r Recipient, err error) error {
+	state := b.Progress()
+	state.Cursor = r.Cursor
+
+	switch {
+	case err == nil:
+		state.Sent++
+	case isBlockedError(err):
+		state.Blocked++
+		if b.onBlocked != nil {
+			b.onBlocked(r.ChatID)
+		}
+	default:
+		state.Failed++
+		b.app.Logger.WarnContext(ctx, "Cannot send broadcast message.", "broadcast", b.id, "chatID", r.ChatID, "error_detail", err)
+	}
+
+	b.setState(state)
+	if err := b.store.Save(ctx, state); err != nil {
+		return err
+	}
+	b.report(state)
+
+	return nil
+}
+
+func (b *Broadcast) stopped(ctx context.Context, err error) error {
+	b.mu.Lock()
+	canceled := b.canceled
+	b.mu.Unlock()
+
+	if canceled {
+		return b.markCanceled(ctx)
+	}
+	return err
+}
+
+// Persist that the broadcast is canceled and return ErrBroadcastCanceled.
+func (b *Broadcast) markCanceled(ctx context.Context) error {
+	state := b.Progress()
+	if state.Canceled {
+		return ErrBroadcastCanceled
+	}
+
+	state.Canceled = true
+	b.setState(state)
+	// ctx is usually canceled by Cancel itself.
+	if err := b.store.Save(context.WithoutCancel(ctx), state); err != nil {
+		return err
+	}
+	b.report(state)
+
+	return ErrBroadcastCanceled
+}
+
+func (b *Broadcast) report(state BroadcastState) {
+	if b.onProgress != nil {
+		b.onProgress(state)
+	}
+}
+
+// Block while the broadcast is paused.
+func (b *Broadcast) wait(ctx context.Context) error {
+	b.mu.Lock()
+	if !b.paused {
+		b.mu.Unlock()
+		return nil
+	}
+	resume := b.resume
+	b.mu.Unlock()
+
+	select {
+	case <-resume:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stop sending after the current recipient until Resume is called.
+func (b *Broadcast) Pause() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.paused {
+		b.paused = true
+		b.resume = make(chan struct{})
+	}
+}
+
+func (b *Broadcast) Resume() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.paused {
+		b.paused = false
+		close(b.resume)
+	}
+}
+
+// Stop the broadcast for good. Run returns ErrBroadcastCanceled, now and
+// after a restart.
+func (b *Broadcast) Cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.canceled = true
+	if b.cancel != nil {
+		b.cancel()
+	}
+}
+
+func (b *Broadcast) Progress() BroadcastState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (b *Broadcast) setState(state BroadcastState) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = state
+}
+
+// Whether err is a rejection of the message that retrying cannot fix: a Bot
+// API error with a 4xx code other than 429.
+func isPermanentError(err error) bool {
+	var tgErr *tgbotapi.Error
+	return errors.As(err, &tgErr) && tgErr.Code >= 400 && tgErr.Code < 500 && tgErr.Code != http.StatusTooManyRequests
+}
+
+// Whether err means the bot cannot write to the chat anymore, because it was
+// blocked, kicked or the user was deactivated.
+func isBlockedError(err error) bool {
+	var tgErr *tgbotapi.Error
+	return errors.As(err, &tgErr) && tgErr.Code == http.StatusForbidden
+}
@@ -0,0 +1,251 @@
+package tgbotapp_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	tgbotapp "github.com/nexoratech2025/go-telegram-bot-app"
+	"github.com/nexoratech2025/go-telegram-bot-app/testutil"
+)
+
+// Return an application on a fake Bot API where the chats in blocked
+// blocked the bot.
+func newBroadcastApp(t *testing.T, blocked ...int64) (*tgbotapp.Application, *testutil.FakeBotAPI) {
+	t.Helper()
+
+	f := testutil.NewFakeBotAPI(t)
+	for _, id := range blocked {
+		f.FailChat(id, http.StatusForbidden, "Forbidden: bot was blocked by the user")
+	}
+
+	app := tgbotapp.New(f.NewBotAPI(t), func(a *tgbotapp.Application) {
+		a.Logger = slog.Default()
+	})
+	return app, f
+}
+
+// Return the chats f delivered messages to.
+func delivered(f *testutil.FakeBotAPI) []int64 {
+	var chats []int64
+	for _, call := range f.Calls() {
+		if strings.HasPrefix(call.Method, "send") && call.ErrorCode == 0 {
+			chatID, _ := strconv.ParseInt(call.Params.Get("chat_id"), 10, 64)
+			chats = append(chats, chatID)
+		}
+	}
+	return chats
+}
+
+func textFactory(ctx context.Context, chatID int64) (tgbotapi.Chattable, error) {
+	return tgbotapi.NewMessage(chatID, "news"), nil
+}
+
+func TestBroadcastShouldSkipAndRecordBlockedRecipients(t *testing.T) {
+	// Arrange
+	app, f := newBroadcastApp(t, 2)
+	var blocked []int64
+	var reports int
+
+	b := tgbotapp.NewBroadcast(app, "news", tgbotapp.ChatIDs([]int64{1, 2, 3}), textFactory,
+		tgbotapp.WithBroadcastLimits(tgbotapp.RateLimits{}),
+		tgbotapp.WithBlockedRecipients(func(chatID int64) { blocked = append(blocked, chatID) }),
+		tgbotapp.WithBroadcastProgress(func(tgbotapp.BroadcastState) { reports++ }),
+	)
+
+	// Act
+	state, err := b.Run(t.Context())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected broadcast to finish, found %v", err)
+	}
+	if !state.Done || state.Sent != 2 || state.Blocked != 1 || state.Failed != 0 {
+		t.Errorf("Expected 2 sent and 1 blocked, found %+v", state)
+	}
+	if len(blocked) != 1 || blocked[0] != 2 {
+		t.Errorf("Expected chat 2 to be reported as blocked, found %v", blocked)
+	}
+	if sent := delivered(f); len(sent) != 2 || sent[0] != 1 || sent[1] != 3 {
+		t.Errorf("Expected messages to chats 1 and 3, found %v", sent)
+	}
+	if reports != 4 {
+		t.Errorf("Expected progress after each recipient and at the end, found %d reports", reports)
+	}
+}
+
+func TestBroadcastShouldSkipBlockedRecipientsOfUploads(t *testing.T) {
+	// Arrange
+	app, f := newBroadcastApp(t, 2)
+	upload := func(ctx context.Context, chatID int64) (tgbotapi.Chattable, error) {
+		return tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{Name: "news.txt", Bytes: []byte("news")}), nil
+	}
+
+	b := tgbotapp.NewBroadcast(app, "news", tgbotapp.ChatIDs([]int64{1, 2, 3}), upload,
+		tgbotapp.WithBroadcastLimits(tgbotapp.RateLimits{}),
+	)
+
+	// Act
+	state, err := b.Run(t.Context())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected broadcast to finish, found %v", err)
+	}
+	if !state.Done || state.Sent != 2 || state.Blocked != 1 {
+		t.Errorf("Expected 2 sent and 1 blocked, found %+v", state)
+	}
+	if sent := delivered(f); len(sent) != 2 || sent[0] != 1 || sent[1] != 3 {
+		t.Errorf("Expected documents to chats 1 and 3, found %v", sent)
+	}
+}
+
+func TestBroadcastShouldResumeAfterStoredCursor(t *testing.T) {
+	// Arrange
+	app, f := newBroadcastApp(t)
+	store := tgbotapp.NewMemoryBroadcastStore()
+	store.Save(t.Context(), tgbotapp.BroadcastState{ID: "news", Cursor: "2", Sent: 2})
+
+	b := tgbotapp.NewBroadcast(app, "news", tgbotapp.ChatIDs([]int64{1, 2, 3}), textFactory,
+		tgbotapp.WithBroadcastLimits(tgbotapp.RateLimits{}),
+		tgbotapp.WithBroadcastStore(store),
+	)
+
+	// Act
+	state, err := b.Run(t.Context())
+
+	// Assert
+	if err != nil || state.Sent != 3 {
+		t.Errorf("Expected 3 sent in total, found %+v, %v", state, err)
+	}
+	if sent := delivered(f); len(sent) != 1 || sent[0] != 3 {
+		t.Errorf("Expected only chat 3 to be messaged, found %v", sent)
+	}
+}
+
+func TestBroadcastCancelShouldStopAndKeepCursor(t *testing.T) {
+	// Arrange
+	app, f := newBroadcastApp(t)
+	store := tgbotapp.NewMemoryBroadcastStore()
+
+	var b *tgbotapp.Broadcast
+	b = tgbotapp.NewBroadcast(app, "news", tgbotapp.ChatIDs([]int64{1, 2, 3}), textFactory,
+		tgbotapp.WithBroadcastLimits(tgbotapp.RateLimits{}),
+		tgbotapp.WithBroadcastStore(store),
+		tgbotapp.WithBroadcastProgress(func(tgbotapp.BroadcastState) { b.Cancel() }),
+	)
+
+	// Act
+	_, err := b.Run(t.Context())
+
+	// Assert
+	if !errors.Is(err, tgbotapp.ErrBroadcastCanceled) {
+		t.Errorf("Expected ErrBroadcastCanceled, found %v", err)
+	}
+	if sent := delivered(f); len(sent) != 1 {
+		t.Errorf("Expected one message before cancel, found %v", sent)
+	}
+
+	state, _, _ := store.Load(t.Context(), "news")
+	if state.Cursor != "1" || state.Done {
+		t.Errorf("Expected cursor after first recipient, found %+v", state)
+	}
+}
+
+func TestBroadcastCancelShouldBePersisted(t *testing.T) {
+	// Arrange
+	app, f := newBroadcastApp(t)
+	store := tgbotapp.NewMemoryBroadcastStore()
+	audience := tgbotapp.ChatIDs([]int64{1, 2})
+
+	canceled := tgbotapp.NewBroadcast(app, "news", audience, textFactory, tgbotapp.WithBroadcastStore(store))
+	canceled.Cancel()
+	canceled.Run(t.Context())
+
+	// Act
+	b := tgbotapp.NewBroadcast(app, "news", audience, textFactory, tgbotapp.WithBroadcastStore(store))
+	state, err := b.Run(t.Context())
+
+	// Assert
+	if !errors.Is(err, tgbotapp.ErrBroadcastCanceled) || !state.Canceled {
+		t.Errorf("Expected canceled broadcast, found %+v, %v", state, err)
+	}
+	if sent := delivered(f); len(sent) != 0 {
+		t.Errorf("Expected no message, found %v", sent)
+	}
+}
+
+func TestBroadcastShouldOnlyCountPermanentErrorsAsFailed(t *testing.T) {
+	// Arrange
+	app, f := newBroadcastApp(t)
+	app.RetryPolicy = tgbotapp.RetryPolicy{MaxAttempts: 1}
+	f.FailChat(2, http.StatusBadRequest, "Bad Request: message text is empty")
+	f.FailChat(3, http.StatusBadGateway, "Bad Gateway")
+	store := tgbotapp.NewMemoryBroadcastStore()
+
+	b := tgbotapp.NewBroadcast(app, "news", tgbotapp.ChatIDs([]int64{1, 2, 3, 4}), textFactory,
+		tgbotapp.WithBroadcastLimits(tgbotapp.RateLimits{}),
+		tgbotapp.WithBroadcastStore(store),
+	)
+
+	// Act
+	state, err := b.Run(t.Context())
+
+	// Assert
+	var tgErr *tgbotapi.Error
+	if !errors.As(err, &tgErr) || tgErr.Code != http.StatusBadGateway {
+		t.Errorf("Expected broadcast to stop at the 502 error, found %v", err)
+	}
+	if state.Sent != 1 || state.Failed != 1 || state.Cursor != "2" || state.Done {
+		t.Errorf("Expected cursor before the transient error, found %+v", state)
+	}
+
+	stored, _, _ := store.Load(t.Context(), "news")
+	if stored.Cursor != "2" {
+		t.Errorf("Expected stored cursor 2, found %+v", stored)
+	}
+}
+
+func TestBroadcastShouldWaitWhilePaused(t *testing.T) {
+	// Arrange
+	app, f := newBroadcastApp(t)
+	paused := make(chan struct{})
+
+	var b *tgbotapp.Broadcast
+	b = tgbotapp.NewBroadcast(app, "news", tgbotapp.ChatIDs([]int64{1, 2, 3}), textFactory,
+		tgbotapp.WithBroadcastLimits(tgbotapp.RateLimits{}),
+		tgbotapp.WithBroadcastProgress(func(s tgbotapp.BroadcastState) {
+			if s.Sent == 1 {
+				b.Pause()
+				close(paused)
+			}
+		}),
+	)
+
+	done := make(chan error)
+	go func() {
+		_, err := b.Run(t.Context())
+		done <- err
+	}()
+
+	// Act
+	<-paused
+	time.Sleep(20 * time.Millisecond)
+	whilePaused := len(delivered(f))
+	b.Resume()
+	err := <-done
+
+	// Assert
+	if whilePaused != 1 {
+		t.Errorf("Expected no message while paused, found %d sent", whilePaused)
+	}
+	if err != nil || len(delivered(f)) != 3 {
+		t.Errorf("Expected all messages after resume, found %v, %v", delivered(f), err)
+	}
+}
@@ -0,0 +1,155 @@
+package tgbotapp
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// Context data key of the chat action indicator of ChatActionMiddleware.
+	ChatActionDataKey = "chat_action"
+
+	// Interval between two chat actions. Telegram shows an action for at
+	// most 5 seconds.
+	ChatActionInterval = 4 * time.Second
+)
+
+// Control the chat action options.
+type ChatActionOption func(*chatActionOptions)
+
+type chatActionOptions struct {
+	clock Clock
+}
+
+// Tell the time with clock. Defaults to SystemClock.
+func WithChatActionClock(clock Clock) ChatActionOption {
+	return func(o *chatActionOptions) {
+		o.clock = clock
+	}
+}
+
+// Show action, such as tgbotapi.ChatTyping, in the chat of each update whose
+// handler runs longer than threshold, until the handler returns. Handlers
+// change the action with HandlerContext.SetChatAction or RouteChatAction.
+// Chat actions are sent at bulk priority, after all other requests.
+func ChatActionMiddleware(action string, threshold time.Duration, opts ...ChatActionOption) Middleware {
+	o := &chatActionOptions{clock: SystemClock}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(ctx *BotContext, next HandlerFunc) {
+		chat := ctx.Update.FromChat()
+		if chat == nil {
+			next(ctx)
+			return
+		}
+
+		indicator := startChatAction(ctx, o.clock, chat.ID, action, threshold)
+		defer indicator.stop()
+
+		ctx.SetData(ChatActionDataKey, indicator)
+		next(ctx)
+	}
+}
+
+// Return handler showing action instead of the action of
+// ChatActionMiddleware.
+func RouteChatAction(action string, handler HandlerFunc) HandlerFunc {
+	return func(ctx *BotContext) {
+		if indicator, ok := chatActionFrom(ctx); ok {
+			indicator.set(action)
+		}
+		handler(ctx)
+	}
+}
+
+// Change the action shown by ChatActionMiddleware while the handler runs.
+// An empty action stops showing one.
+func (h *HandlerContext) SetChatAction(action string) {
+	if indicator, ok := chatActionFrom(h.BotContext); ok {
+		indicator.set(action)
+	}
+}
+
+// Show action in the current chat until stop is called.
+func (h *HandlerContext) KeepChatAction(action string) (stop func()) {
+	return startChatAction(h.BotContext, SystemClock, h.GetChatID(), action, 0).stop
+}
+
+func chatActionFrom(ctx *BotContext) (*chatActionIndicator, bool) {
+	v, _ := ctx.GetData(ChatActionDataKey)
+	indicator, ok := v.(*chatActionIndicator)
+	return indicator, ok
+}
+
+type chatActionIndicator struct {
+	ctx    *BotContext
+	clock  Clock
+	chatID int64
+
+	mu     sync.Mutex
+	action string
+
+	// Cancels the running loop and its request in progress.
+	cancel  context.CancelFunc
+	stopped chan struct{}
+}
+
+func startChatAction(ctx *BotContext, clock Clock, chatID int64, action string, delay time.Duration) *chatActionIndicator {
+	runCtx, cancel := context.WithCancel(ctx.Ctx)
+
+	a := &chatActionIndicator{
+		ctx:     ctx,
+		clock:   clock,
+		chatID:  chatID,
+		action:  action,
+		cancel:  cancel,
+		stopped: make(chan struct{}),
+	}
+
+	go a.run(runCtx, clock.Now().Add(delay))
+
+	return a
+}
+
+func (a *chatActionIndicator) run(ctx context.Context, next time.Time) {
+	defer close(a.stopped)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.clock.Until(next):
+		}
+
+		a.mu.Lock()
+		action := a.action
+		a.mu.Unlock()
+
+		if action != "" {
+			reqCtx := a.ctx.outboundContext(WithPriority(ctx, PriorityBulk))
+			_, err := a.ctx.sender().Request(reqCtx, a.chatID, tgbotapi.NewChatAction(a.chatID, action))
+			if err != nil && ctx.Err() == nil {
+				a.ctx.Logger().DebugContext(ctx, "Cannot send chat action.", "action", action, "error_detail", err)
+			}
+		}
+
+		next = next.Add(ChatActionInterval)
+	}
+}
+
+func (a *chatActionIndicator) set(action string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.action = action
+}
+
+// Stop showing the action, canceling a request in progress.
+func (a *chatActionIndicator) stop() {
+	a.cancel()
+	<-a.stopped
+}
@@ -0,0 +1,126 @@
+package tgbotapp_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	tgbotapp "github.com/nexoratech2025/go-telegram-bot-app"
+	"github.com/nexoratech2025/go-telegram-bot-app/testutil"
+)
+
+// Wait until f received at least n chat actions.
+func waitChatActions(t *testing.T, f *testutil.FakeBotAPI, n int) {
+	t.Helper()
+	f.WaitForCall(t, "sendChatAction", n, time.Second)
+}
+
+func TestChatActionMiddlewareShouldShowActionForSlowHandlers(t *testing.T) {
+	// Arrange
+	h, f := newHandlerContext(t)
+	clock := testutil.NewFakeClock(jobsEpoch)
+	mw := tgbotapp.ChatActionMiddleware(tgbotapi.ChatTyping, time.Second, tgbotapp.WithChatActionClock(clock))
+
+	// Act
+	mw(h.BotContext, func(*tgbotapp.BotContext) {
+		clock.Advance(time.Second)
+		waitChatActions(t, f, 1)
+	})
+
+	// Assert
+	if _, params := lastCall(f, "sendChatAction"); params.Get("action") != tgbotapi.ChatTyping {
+		t.Errorf("Expected typing action, found %v", params)
+	}
+}
+
+func TestChatActionMiddlewareShouldNotShowActionForFastHandlers(t *testing.T) {
+	// Arrange
+	h, f := newHandlerContext(t)
+	clock := testutil.NewFakeClock(jobsEpoch)
+	mw := tgbotapp.ChatActionMiddleware(tgbotapi.ChatTyping, time.Second, tgbotapp.WithChatActionClock(clock))
+
+	// Act
+	mw(h.BotContext, func(*tgbotapp.BotContext) {
+		clock.Advance(time.Second - time.Millisecond)
+	})
+	clock.Advance(time.Hour)
+
+	// Assert
+	if calls, _ := lastCall(f, "sendChatAction"); calls != 0 {
+		t.Errorf("Expected no chat action, found %d", calls)
+	}
+}
+
+func TestRouteChatActionShouldOverrideAction(t *testing.T) {
+	// Arrange
+	h, f := newHandlerContext(t)
+	clock := testutil.NewFakeClock(jobsEpoch)
+	mw := tgbotapp.ChatActionMiddleware(tgbotapi.ChatTyping, time.Second, tgbotapp.WithChatActionClock(clock))
+
+	// Act
+	mw(h.BotContext, tgbotapp.RouteChatAction(tgbotapi.ChatUploadDocument, func(*tgbotapp.BotContext) {
+		clock.Advance(time.Second)
+		waitChatActions(t, f, 1)
+	}))
+
+	// Assert
+	if _, params := lastCall(f, "sendChatAction"); params.Get("action") != tgbotapi.ChatUploadDocument {
+		t.Errorf("Expected upload_document action, found %v", params)
+	}
+}
+
+// Sender blocking every request until it is canceled, reporting the
+// priority of the request on requests.
+type blockingSender struct {
+	requests chan tgbotapp.Priority
+}
+
+func (s *blockingSender) Request(ctx context.Context, chatID int64, c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	s.requests <- tgbotapp.PriorityFrom(ctx, tgbotapp.PriorityInteractive)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestChatActionMiddlewareShouldCancelPendingActionWhenHandlerReturns(t *testing.T) {
+	// Arrange
+	sender := &blockingSender{requests: make(chan tgbotapp.Priority, 1)}
+	h := newFakeSenderHandlerContext(t, sender)
+	mw := tgbotapp.ChatActionMiddleware(tgbotapi.ChatTyping, 0)
+
+	var priority tgbotapp.Priority
+	done := make(chan struct{})
+
+	// Act
+	go func() {
+		mw(h.BotContext, func(*tgbotapp.BotContext) {
+			priority = <-sender.requests
+		})
+		close(done)
+	}()
+
+	// Assert
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected middleware not to wait for the chat action")
+	}
+	if priority != tgbotapp.PriorityBulk {
+		t.Errorf("Expected chat action at bulk priority, found %v", priority)
+	}
+}
+
+func TestKeepChatActionShouldShowActionUntilStopped(t *testing.T) {
+	// Arrange
+	h, f := newHandlerContext(t)
+
+	// Act
+	stop := h.KeepChatAction(tgbotapi.ChatUploadPhoto)
+	waitChatActions(t, f, 1)
+	stop()
+
+	// Assert
+	if _, params := lastCall(f, "sendChatAction"); params.Get("action") != tgbotapi.ChatUploadPhoto {
+		t.Errorf("Expected upload_photo action, found %v", params)
+	}
+}
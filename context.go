@@ -16,14 +16,18 @@ const (
 type HandlerFunc func(*BotContext)
 
 type BotContext struct {
-	data map[string]any
-	app  *Application
+	data    map[string]any
+	app     *Application
+	handler HandlerFunc
 
 	Ctx     context.Context
 	BotAPI  *tgbotapi.BotAPI
 	Update  *tgbotapi.Update
 	Session session.Sessioner
 	Params  []string
+	// Sender of the outbound requests made with the context. Defaults to the
+	// application's sender.
+	Sender Sender
 }
 
 func NewBotContext(ctx context.Context, app *Application, update *tgbotapi.Update) *BotContext {
@@ -41,6 +45,7 @@ func NewBotContext(ctx context.Context, app *Application, update *tgbotapi.Updat
 
 	if app != nil {
 		c.BotAPI = app.BotAPI
+		c.Sender = app.newSender(app.Outbound)
 	}
 
 	return c
@@ -59,6 +64,35 @@ func (c *BotContext) Logger() *slog.Logger {
 	return c.app.Logger
 }
 
+// Set the handler of the update, run once all middlewares called next.
 func (c *BotContext) SetHandler(f HandlerFunc) {
-	c.app.handler = f
+	c.handler = f
+}
+
+// Send msg to chatID through the application's sender.
+func (c *BotContext) send(chatID int64, msg tgbotapi.Chattable) (res tgbotapi.Message, err error) {
+	err = c.requestResult(c.Ctx, chatID, msg, &res)
+	return
+}
+
+// Same as send for methods that do not return a message.
+func (c *BotContext) request(chatID int64, msg tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	return c.sender().Request(c.outboundContext(c.Ctx), chatID, msg)
+}
+
+// Make request msg to chatID and decode its result into v.
+func (c *BotContext) requestResult(ctx context.Context, chatID int64, msg tgbotapi.Chattable, v any) error {
+	return requestResult(c.outboundContext(ctx), c.sender(), chatID, msg, v)
+}
+
+func (c *BotContext) sender() Sender {
+	if c.Sender != nil {
+		return c.Sender
+	}
+	return NewBotSender(c.BotAPI)
+}
+
+// Requests made while handling an update are replies to a user.
+func (c *BotContext) outboundContext(ctx context.Context) context.Context {
+	return WithPriority(ctx, PriorityFrom(ctx, PriorityInteractive))
 }
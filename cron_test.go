@@ -0,0 +1,72 @@
+package tgbotapp_test
+
+import (
+	"testing"
+	"time"
+
+	tgbotapp "github.com/nexoratech2025/go-telegram-bot-app"
+)
+
+func TestParseCronShouldComputeNextTime(t *testing.T) {
+	// Monday, 15 January 2024 10:30.
+	from := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2024, 1, 15, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 1, 15, 10, 45, 0, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2024, 1, 16, 9, 0, 0, 0, time.UTC)},
+		{"0 9-17/4 * * mon-fri", time.Date(2024, 1, 15, 13, 0, 0, 0, time.UTC)},
+		{"0 0 * * SUN", time.Date(2024, 1, 21, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 1, 21, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1,15 * 3", time.Date(2024, 1, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 90s", from.Add(90 * time.Second)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			// Arrange
+			schedule, err := tgbotapp.ParseCron(tt.spec)
+			if err != nil {
+				t.Fatalf("Expected valid expression, found %v", err)
+			}
+
+			// Act
+			next := schedule.Next(from)
+
+			// Assert
+			if !next.Equal(tt.expected) {
+				t.Errorf("Expected %v, found %v", tt.expected, next)
+			}
+		})
+	}
+}
+
+func TestParseCronShouldRejectInvalidExpressions(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* * * foo *", "5-1 * * * *", "*/0 * * * *", "@every -1m"} {
+		// Act
+		_, err := tgbotapp.ParseCron(spec)
+
+		// Assert
+		if err == nil {
+			t.Errorf("Expected %q to be rejected", spec)
+		}
+	}
+}
+
+func TestParseCronShouldNeverMatchImpossibleDates(t *testing.T) {
+	// Arrange
+	schedule, _ := tgbotapp.ParseCron("0 0 30 2 *")
+
+	// Act
+	next := schedule.Next(time.Now())
+
+	// Assert
+	if !next.IsZero() {
+		t.Errorf("Expected no next time, found %v", next)
+	}
+}
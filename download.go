@@ -0,0 +1,191 @@
+package tgbotapp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// Largest file the Bot API lets bots download.
+	MaxDownloadSize = 20 << 20
+)
+
+var (
+	ErrFileTooLarge     = errors.New("File exceeds the maximum download size.")
+	ErrChecksumMismatch = errors.New("Downloaded file does not match the expected checksum.")
+	ErrNoBotAPI         = errors.New("Handler has no Bot API to download files with.")
+)
+
+// Control how a file is downloaded.
+type DownloadOption func(*downloadOptions)
+
+type downloadOptions struct {
+	maxSize  int64
+	checksum string
+}
+
+// Fail with ErrFileTooLarge if the file is larger than n bytes. Defaults to
+// MaxDownloadSize.
+func MaxSize(n int64) DownloadOption {
+	return func(o *downloadOptions) {
+		o.maxSize = n
+	}
+}
+
+// Fail with ErrChecksumMismatch if the hex encoded SHA-256 checksum of the
+// file is not sum.
+func ExpectChecksum(sum string) DownloadOption {
+	return func(o *downloadOptions) {
+		o.checksum = sum
+	}
+}
+
+// File downloaded by the HandlerContext download helpers.
+type DownloadedFile struct {
+	FileID       string
+	FileUniqueID string
+	// Path of the file on Telegram's servers.
+	FilePath string
+	// Number of bytes downloaded.
+	Size int64
+	// Hex encoded SHA-256 checksum of the content.
+	SHA256 string
+}
+
+// Download the file fileID, such as the one of GetDocument or GetBestPhoto,
+// to w. Nothing is written to w if the file is known to be too large, but w
+// may hold part of the file if the download fails. Downloads need the BotAPI
+// of the handler and fail with ErrNoBotAPI without one.
+func (h *HandlerContext) DownloadFile(fileID string, w io.Writer, opts ...DownloadOption) (DownloadedFile, error) {
+	if h.BotAPI == nil {
+		return DownloadedFile{}, ErrNoBotAPI
+	}
+
+	o := &downloadOptions{maxSize: MaxDownloadSize}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	cfg := tgbotapi.FileConfig{FileID: fileID}
+
+	// getFile sends nothing to the chat, so it is not limited per chat.
+	var file tgbotapi.File
+	if err := h.requestResult(h.Ctx, 0, cfg, &file); err != nil {
+		return DownloadedFile{}, err
+	}
+
+	res := DownloadedFile{
+		FileID:       file.FileID,
+		FileUniqueID: file.FileUniqueID,
+		FilePath:     file.FilePath,
+	}
+
+	if o.maxSize > 0 && int64(file.FileSize) > o.maxSize {
+		return res, ErrFileTooLarge
+	}
+
+	req, err := http.NewRequestWithContext(h.Ctx, http.MethodGet, h.fileURL(file), nil)
+	if err != nil {
+		return res, err
+	}
+
+	// File contents are not Bot API requests, so they bypass the sender.
+	resp, err := h.BotAPI.Client.Do(req)
+	if err != nil {
+		return res, redactToken(err, h.BotAPI.Token)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return res, fmt.Errorf("Cannot download file %s: %s", file.FilePath, resp.Status)
+	}
+
+	var body io.Reader = resp.Body
+	if o.maxSize > 0 {
+		// Read one byte more to detect files larger than announced.
+		body = io.LimitReader(body, o.maxSize+1)
+	}
+
+	sum := sha256.New()
+	res.Size, err = io.Copy(io.MultiWriter(w, sum), body)
+	res.SHA256 = hex.EncodeToString(sum.Sum(nil))
+	if err != nil {
+		return res, err
+	}
+
+	if o.maxSize > 0 && res.Size > o.maxSize {
+		return res, ErrFileTooLarge
+	}
+	if o.checksum != "" && o.checksum != res.SHA256 {
+		return res, ErrChecksumMismatch
+	}
+
+	return res, nil
+}
+
+// Same as DownloadFile, returning the content.
+func (h *HandlerContext) DownloadFileBytes(fileID string, opts ...DownloadOption) ([]byte, DownloadedFile, error) {
+	var buf bytes.Buffer
+	res, err := h.DownloadFile(fileID, &buf, opts...)
+	if err != nil {
+		return nil, res, err
+	}
+	return buf.Bytes(), res, nil
+}
+
+// Same as DownloadFile, storing the content in a new temporary file whose
+// name keeps the extension of the file. The caller must remove the file.
+func (h *HandlerContext) DownloadTempFile(fileID string, opts ...DownloadOption) (string, DownloadedFile, error) {
+	f, err := os.CreateTemp("", "tgbotapp-*")
+	if err != nil {
+		return "", DownloadedFile{}, err
+	}
+
+	res, err := h.DownloadFile(fileID, f, opts...)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", res, err
+	}
+
+	name := f.Name()
+	if ext := path.Ext(res.FilePath); ext != "" {
+		if err := os.Rename(name, name+ext); err != nil {
+			os.Remove(name)
+			return "", res, err
+		}
+		name += ext
+	}
+
+	return name, res, nil
+}
+
+func (h *HandlerContext) fileURL(file tgbotapi.File) string {
+	endpoint := tgbotapi.FileEndpoint
+	if h.app != nil && h.app.FileEndpoint != "" {
+		endpoint = h.app.FileEndpoint
+	}
+	return fmt.Sprintf(endpoint, h.BotAPI.Token, file.FilePath)
+}
+
+// Remove token from the URL of err, which the file URL includes.
+func redactToken(err error, token string) error {
+	var urlErr *url.Error
+	if token != "" && errors.As(err, &urlErr) {
+		urlErr.URL = strings.ReplaceAll(urlErr.URL, token, "<token>")
+	}
+	return err
+}
@@ -0,0 +1,184 @@
+package tgbotapp_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	tgbotapp "github.com/nexoratech2025/go-telegram-bot-app"
+	"github.com/nexoratech2025/go-telegram-bot-app/testutil"
+)
+
+// Return a handler context whose bot serves content as the file doc.
+func newDownloadHandlerContext(t *testing.T, content string) *tgbotapp.HandlerContext {
+	t.Helper()
+
+	h, f := newHandlerContext(t)
+	f.AddFile("doc", "documents/a.txt", []byte(content))
+	return h
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestDownloadFileShouldWriteContentAndChecksum(t *testing.T) {
+	// Arrange
+	h := newDownloadHandlerContext(t, "hello")
+	var buf strings.Builder
+
+	// Act
+	file, err := h.DownloadFile("doc", &buf)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected file to be downloaded, found %v", err)
+	}
+	if buf.String() != "hello" {
+		t.Errorf("Expected content hello, found %q", buf.String())
+	}
+	if file.FileID != "doc" || file.Size != 5 || file.SHA256 != sha256Hex("hello") {
+		t.Errorf("Expected file details, found %+v", file)
+	}
+}
+
+func TestDownloadFileShouldRejectAnnouncedLargeFiles(t *testing.T) {
+	// Arrange
+	h := newDownloadHandlerContext(t, "hello")
+	var buf strings.Builder
+
+	// Act
+	_, err := h.DownloadFile("doc", &buf, tgbotapp.MaxSize(4))
+
+	// Assert
+	if !errors.Is(err, tgbotapp.ErrFileTooLarge) {
+		t.Errorf("Expected ErrFileTooLarge, found %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected nothing to be written, found %q", buf.String())
+	}
+}
+
+func TestDownloadFileShouldStopAtMaxSize(t *testing.T) {
+	// Arrange
+	h, f := newHandlerContext(t)
+	f.AddFileWithSize("doc", "documents/a.txt", []byte("hello world"), 0)
+
+	// Act
+	_, _, err := h.DownloadFileBytes("doc", tgbotapp.MaxSize(5))
+
+	// Assert
+	if !errors.Is(err, tgbotapp.ErrFileTooLarge) {
+		t.Errorf("Expected ErrFileTooLarge, found %v", err)
+	}
+}
+
+func TestDownloadFileShouldVerifyChecksum(t *testing.T) {
+	// Arrange
+	h := newDownloadHandlerContext(t, "hello")
+
+	// Act
+	_, _, err := h.DownloadFileBytes("doc", tgbotapp.ExpectChecksum(sha256Hex("other")))
+
+	// Assert
+	if !errors.Is(err, tgbotapp.ErrChecksumMismatch) {
+		t.Errorf("Expected ErrChecksumMismatch, found %v", err)
+	}
+}
+
+func TestDownloadFileShouldStopWhenContextIsCanceled(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(t.Context())
+	h := newDownloadHandlerContext(t, "hello")
+	h.Ctx = ctx
+	cancel()
+
+	// Act
+	_, _, err := h.DownloadFileBytes("doc")
+
+	// Assert
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, found %v", err)
+	}
+}
+
+func TestDownloadFileShouldNotLeakTokenInErrors(t *testing.T) {
+	// Arrange
+	h, f := newHandlerContext(t, tgbotapp.WithFileEndpoint("http://127.0.0.1:0/file/bot%s/%s"))
+	f.AddFile("doc", "documents/a.txt", []byte("hello"))
+
+	// Act
+	_, err := h.DownloadFile("doc", io.Discard)
+
+	// Assert
+	if err == nil || strings.Contains(err.Error(), testutil.FakeToken) {
+		t.Errorf("Expected error without token, found %v", err)
+	}
+}
+
+func TestDownloadFileShouldNotRequestFileForTheChat(t *testing.T) {
+	// Arrange
+	var chats []int64
+	record := func(next tgbotapp.Sender) tgbotapp.Sender {
+		return tgbotapp.SenderFunc(func(ctx context.Context, chatID int64, c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+			chats = append(chats, chatID)
+			return next.Request(ctx, chatID, c)
+		})
+	}
+	h, f := newHandlerContext(t, tgbotapp.WithSenderMiddleware(record))
+	f.AddFile("doc", "documents/a.txt", []byte("hello"))
+
+	// Act
+	_, err := h.DownloadFile("doc", io.Discard)
+
+	// Assert
+	if err != nil || len(chats) != 1 || chats[0] != 0 {
+		t.Errorf("Expected getFile for chat 0, found %v, %v", chats, err)
+	}
+}
+
+func TestDownloadFileShouldFailWithoutBotAPI(t *testing.T) {
+	// Arrange
+	sender := &fakeSender{}
+	h := newFakeSenderHandlerContext(t, sender)
+
+	// Act
+	_, err := h.DownloadFile("doc", io.Discard)
+
+	// Assert
+	if !errors.Is(err, tgbotapp.ErrNoBotAPI) {
+		t.Errorf("Expected ErrNoBotAPI, found %v", err)
+	}
+	if len(sender.calls) != 0 {
+		t.Errorf("Expected no request, found %+v", sender.calls)
+	}
+}
+
+func TestDownloadTempFileShouldKeepExtension(t *testing.T) {
+	// Arrange
+	h := newDownloadHandlerContext(t, "hello")
+
+	// Act
+	name, _, err := h.DownloadTempFile("doc")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected file to be downloaded, found %v", err)
+	}
+	defer os.Remove(name)
+
+	if filepath.Ext(name) != ".txt" {
+		t.Errorf("Expected .txt extension, found %s", name)
+	}
+	if b, _ := os.ReadFile(name); string(b) != "hello" {
+		t.Errorf("Expected content hello, found %q", b)
+	}
+}
@@ -0,0 +1,423 @@
+// Package format builds formatted message texts that are safe to send with
+// any user-supplied content. The same text can be rendered as HTML, as
+// MarkdownV2 or as plain text with message entities.
+package format
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Entity types, as used by Telegram message entities.
+const (
+	TypeBold          = "bold"
+	TypeItalic        = "italic"
+	TypeUnderline     = "underline"
+	TypeStrikethrough = "strikethrough"
+	TypeSpoiler       = "spoiler"
+	TypeCode          = "code"
+	TypePre           = "pre"
+	TypeTextLink      = "text_link"
+	TypeTextMention   = "text_mention"
+
+	// Markup that is inserted without escaping.
+	TypeRaw = "raw"
+)
+
+// Node is a piece of formatted text. A node without a type is plain text.
+type Node struct {
+	Type     string
+	Text     string
+	URL      string
+	UserID   int64
+	Language string
+	Children []Node
+}
+
+// Message is a rendered text ready to be sent. Either ParseMode or Entities
+// is set, depending on how it was rendered.
+type Message struct {
+	Text      string
+	ParseMode string
+	Entities  []tgbotapi.MessageEntity
+}
+
+// Plain returns unformatted text. Values that are not strings or nodes are
+// formatted with fmt.Sprint.
+func Plain(content ...any) Node {
+	return group("", content)
+}
+
+func Bold(content ...any) Node {
+	return group(TypeBold, content)
+}
+
+func Italic(content ...any) Node {
+	return group(TypeItalic, content)
+}
+
+func Underline(content ...any) Node {
+	return group(TypeUnderline, content)
+}
+
+func Strikethrough(content ...any) Node {
+	return group(TypeStrikethrough, content)
+}
+
+func Spoiler(content ...any) Node {
+	return group(TypeSpoiler, content)
+}
+
+// Raw returns text that is already formatted for the parse mode it will be
+// rendered with. It is inserted without escaping, so it must never contain
+// user-supplied text.
+func Raw(text string) Node {
+	return Node{Type: TypeRaw, Text: text}
+}
+
+// Code returns inline monospace text. Code cannot contain other formatting.
+func Code(text string) Node {
+	return Node{Type: TypeCode, Text: text}
+}
+
+// Pre returns a monospace block, highlighted as language if it is not empty.
+func Pre(language, text string) Node {
+	return Node{Type: TypePre, Text: text, Language: language}
+}
+
+func Link(url string, content ...any) Node {
+	n := group(TypeTextLink, content)
+	n.URL = url
+	return n
+}
+
+// Mention links to a user by id, which works for users without a username.
+func Mention(userID int64, content ...any) Node {
+	n := group(TypeTextMention, content)
+	n.UserID = userID
+	return n
+}
+
+func group(typ string, content []any) Node {
+	n := Node{Type: typ}
+
+	for _, c := range content {
+		switch v := c.(type) {
+		case Node:
+			n.Children = append(n.Children, v)
+		case string:
+			n.Children = append(n.Children, Node{Text: v})
+		default:
+			n.Children = append(n.Children, Node{Text: fmt.Sprint(v)})
+		}
+	}
+
+	// Avoid a level of nesting for the common single string case.
+	if len(n.Children) == 1 && n.Children[0].Type == "" && len(n.Children[0].Children) == 0 {
+		n.Text, n.Children = n.Children[0].Text, nil
+	}
+
+	return n
+}
+
+// Builder appends formatted pieces of text one after another.
+type Builder struct {
+	nodes []Node
+}
+
+func New() *Builder {
+	return &Builder{}
+}
+
+func (b *Builder) Add(nodes ...Node) *Builder {
+	b.nodes = append(b.nodes, nodes...)
+	return b
+}
+
+func (b *Builder) Text(content ...any) *Builder {
+	return b.Add(Plain(content...))
+}
+
+// Textf appends plain text formatted with fmt.Sprintf.
+func (b *Builder) Textf(format string, args ...any) *Builder {
+	return b.Add(Node{Text: fmt.Sprintf(format, args...)})
+}
+
+func (b *Builder) Bold(content ...any) *Builder {
+	return b.Add(Bold(content...))
+}
+
+func (b *Builder) Italic(content ...any) *Builder {
+	return b.Add(Italic(content...))
+}
+
+func (b *Builder) Underline(content ...any) *Builder {
+	return b.Add(Underline(content...))
+}
+
+func (b *Builder) Strikethrough(content ...any) *Builder {
+	return b.Add(Strikethrough(content...))
+}
+
+func (b *Builder) Spoiler(content ...any) *Builder {
+	return b.Add(Spoiler(content...))
+}
+
+func (b *Builder) Code(text string) *Builder {
+	return b.Add(Code(text))
+}
+
+func (b *Builder) Pre(language, text string) *Builder {
+	return b.Add(Pre(language, text))
+}
+
+func (b *Builder) Link(url string, content ...any) *Builder {
+	return b.Add(Link(url, content...))
+}
+
+func (b *Builder) Mention(userID int64, content ...any) *Builder {
+	return b.Add(Mention(userID, content...))
+}
+
+func (b *Builder) Nodes() []Node {
+	return b.nodes
+}
+
+func (b *Builder) HTML() Message {
+	return HTML(b.nodes...)
+}
+
+func (b *Builder) MarkdownV2() Message {
+	return MarkdownV2(b.nodes...)
+}
+
+func (b *Builder) Entities() Message {
+	return Entities(b.nodes...)
+}
+
+// HTML renders nodes with parse mode HTML.
+func HTML(nodes ...Node) Message {
+	var sb strings.Builder
+	for _, n := range nodes {
+		writeHTML(&sb, n)
+	}
+	return Message{Text: sb.String(), ParseMode: tgbotapi.ModeHTML}
+}
+
+var htmlTags = map[string]string{
+	TypeBold:          "b",
+	TypeItalic:        "i",
+	TypeUnderline:     "u",
+	TypeStrikethrough: "s",
+	TypeSpoiler:       "tg-spoiler",
+	TypeCode:          "code",
+}
+
+func writeHTML(sb *strings.Builder, n Node) {
+	switch n.Type {
+	case TypeRaw:
+		sb.WriteString(n.Text)
+
+	case "":
+		sb.WriteString(EscapeHTML(n.Text))
+		writeHTMLChildren(sb, n)
+
+	case TypePre:
+		if n.Language != "" {
+			sb.WriteString(`<pre><code class="language-` + EscapeHTML(n.Language) + `">`)
+			sb.WriteString(EscapeHTML(n.Text))
+			sb.WriteString("</code></pre>")
+			return
+		}
+		sb.WriteString("<pre>" + EscapeHTML(n.Text) + "</pre>")
+
+	case TypeTextLink, TypeTextMention:
+		url := n.URL
+		if n.Type == TypeTextMention {
+			url = mentionURL(n.UserID)
+		}
+		sb.WriteString(`<a href="` + EscapeHTML(url) + `">`)
+		sb.WriteString(EscapeHTML(n.Text))
+		writeHTMLChildren(sb, n)
+		sb.WriteString("</a>")
+
+	default:
+		tag := htmlTags[n.Type]
+		sb.WriteString("<" + tag + ">")
+		sb.WriteString(EscapeHTML(n.Text))
+		writeHTMLChildren(sb, n)
+		sb.WriteString("</" + tag + ">")
+	}
+}
+
+func writeHTMLChildren(sb *strings.Builder, n Node) {
+	for _, c := range n.Children {
+		writeHTML(sb, c)
+	}
+}
+
+// MarkdownV2 renders nodes with parse mode MarkdownV2.
+func MarkdownV2(nodes ...Node) Message {
+	var sb strings.Builder
+	for _, n := range nodes {
+		writeMarkdown(&sb, n)
+	}
+	return Message{Text: sb.String(), ParseMode: tgbotapi.ModeMarkdownV2}
+}
+
+var markdownMarkers = map[string]string{
+	TypeBold:          "*",
+	TypeItalic:        "_",
+	TypeUnderline:     "__",
+	TypeStrikethrough: "~",
+	TypeSpoiler:       "||",
+}
+
+func writeMarkdown(sb *strings.Builder, n Node) {
+	switch n.Type {
+	case TypeRaw:
+		sb.WriteString(n.Text)
+
+	case "":
+		sb.WriteString(EscapeMarkdownV2(n.Text))
+		writeMarkdownChildren(sb, n)
+
+	case TypeCode:
+		sb.WriteString("`" + escapeMarkdownCode(n.Text) + "`")
+
+	case TypePre:
+		sb.WriteString("```" + n.Language + "\n" + escapeMarkdownCode(n.Text) + "\n```")
+
+	case TypeTextLink, TypeTextMention:
+		url := n.URL
+		if n.Type == TypeTextMention {
+			url = mentionURL(n.UserID)
+		}
+		sb.WriteString("[")
+		sb.WriteString(EscapeMarkdownV2(n.Text))
+		writeMarkdownChildren(sb, n)
+		sb.WriteString("](" + escapeMarkdownURL(url) + ")")
+
+	default:
+		marker := markdownMarkers[n.Type]
+		writeMarker(sb, marker)
+		sb.WriteString(EscapeMarkdownV2(n.Text))
+		writeMarkdownChildren(sb, n)
+		writeMarker(sb, marker)
+	}
+}
+
+// Italic and underline markers next to each other are ambiguous; Telegram
+// recommends separating them with a carriage return.
+func writeMarker(sb *strings.Builder, marker string) {
+	if marker[0] == '_' && strings.HasSuffix(sb.String(), "_") && !strings.HasSuffix(sb.String(), `\_`) {
+		sb.WriteString("\r")
+	}
+	sb.WriteString(marker)
+}
+
+func writeMarkdownChildren(sb *strings.Builder, n Node) {
+	for _, c := range n.Children {
+		writeMarkdown(sb, c)
+	}
+}
+
+// Entities renders nodes as plain text with message entities. Offsets and
+// lengths are in UTF-16 code units, as Telegram expects.
+func Entities(nodes ...Node) Message {
+	r := entityRenderer{}
+	for _, n := range nodes {
+		r.write(n)
+	}
+	return Message{Text: r.sb.String(), Entities: r.entities}
+}
+
+type entityRenderer struct {
+	sb       strings.Builder
+	offset   int
+	entities []tgbotapi.MessageEntity
+}
+
+func (r *entityRenderer) write(n Node) {
+	start := r.offset
+	index := len(r.entities)
+
+	if n.Type != "" && n.Type != TypeRaw {
+		e := tgbotapi.MessageEntity{Type: n.Type, Offset: start}
+		switch n.Type {
+		case TypeTextLink:
+			e.URL = n.URL
+		case TypeTextMention:
+			e.User = &tgbotapi.User{ID: n.UserID}
+		case TypePre:
+			e.Language = n.Language
+		}
+		r.entities = append(r.entities, e)
+	}
+
+	r.sb.WriteString(n.Text)
+	r.offset += utf16Len(n.Text)
+	for _, c := range n.Children {
+		r.write(c)
+	}
+
+	if n.Type == "" || n.Type == TypeRaw {
+		return
+	}
+
+	if r.offset == start {
+		// Telegram rejects empty entities.
+		r.entities = append(r.entities[:index], r.entities[index+1:]...)
+		return
+	}
+	r.entities[index].Length = r.offset - start
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+func mentionURL(userID int64) string {
+	return "tg://user?id=" + strconv.FormatInt(userID, 10)
+}
+
+var (
+	htmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+	markdownEscaper = strings.NewReplacer(
+		`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`,
+		")", `\)`, "~", `\~`, "`", "\\`", ">", `\>`, "#", `\#`, "+", `\+`,
+		"-", `\-`, "=", `\=`, "|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`,
+		"!", `\!`,
+	)
+
+	markdownCodeEscaper = strings.NewReplacer(`\`, `\\`, "`", "\\`")
+	markdownURLEscaper  = strings.NewReplacer(`\`, `\\`, ")", `\)`)
+)
+
+// EscapeHTML escapes text for parse mode HTML.
+func EscapeHTML(text string) string {
+	return htmlEscaper.Replace(text)
+}
+
+// EscapeMarkdownV2 escapes text for parse mode MarkdownV2, outside of code
+// and links.
+func EscapeMarkdownV2(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+func escapeMarkdownCode(text string) string {
+	return markdownCodeEscaper.Replace(text)
+}
+
+func escapeMarkdownURL(url string) string {
+	return markdownURLEscaper.Replace(url)
+}
@@ -0,0 +1,146 @@
+package format_test
+
+import (
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nexoratech2025/go-telegram-bot-app/format"
+)
+
+func TestHTMLShouldEscapeUserText(t *testing.T) {
+	// Act
+	msg := format.New().Text("a < b & ").Bold("<script>").Link(`https://x.y/?a="1"&b`, "go").HTML()
+
+	// Assert
+	expected := `a &lt; b &amp; <b>&lt;script&gt;</b><a href="https://x.y/?a=&quot;1&quot;&amp;b">go</a>`
+	if msg.Text != expected {
+		t.Errorf("Expected %q, found %q", expected, msg.Text)
+	}
+	if msg.ParseMode != tgbotapi.ModeHTML {
+		t.Errorf("Expected parse mode HTML, found %q", msg.ParseMode)
+	}
+}
+
+func TestHTMLShouldRenderAllTypes(t *testing.T) {
+	// Act
+	msg := format.HTML(
+		format.Italic("i"),
+		format.Underline("u"),
+		format.Strikethrough("s"),
+		format.Spoiler("p"),
+		format.Code("c<"),
+		format.Pre("go", "x"),
+		format.Mention(42, "me"),
+	)
+
+	// Assert
+	expected := `<i>i</i><u>u</u><s>s</s><tg-spoiler>p</tg-spoiler><code>c&lt;</code>` +
+		`<pre><code class="language-go">x</code></pre><a href="tg://user?id=42">me</a>`
+	if msg.Text != expected {
+		t.Errorf("Expected %q, found %q", expected, msg.Text)
+	}
+}
+
+func TestMarkdownV2ShouldEscapeUserText(t *testing.T) {
+	// Act
+	msg := format.New().Text("1+1=2. Done!").Bold("snake_case *x*").Code("a`b\\").Link("https://x.y/(1)", "[go]").MarkdownV2()
+
+	// Assert
+	expected := "1\\+1\\=2\\. Done\\!*snake\\_case \\*x\\**`a\\`b\\\\`[\\[go\\]](https://x.y/(1\\))"
+	if msg.Text != expected {
+		t.Errorf("Expected %q, found %q", expected, msg.Text)
+	}
+	if msg.ParseMode != tgbotapi.ModeMarkdownV2 {
+		t.Errorf("Expected parse mode MarkdownV2, found %q", msg.ParseMode)
+	}
+}
+
+func TestMarkdownV2ShouldNestEntities(t *testing.T) {
+	// Act
+	msg := format.MarkdownV2(format.Bold("a ", format.Italic("b"), format.Spoiler("c")), format.Pre("", "x"))
+
+	// Assert
+	expected := "*a _b_||c||*```\nx\n```"
+	if msg.Text != expected {
+		t.Errorf("Expected %q, found %q", expected, msg.Text)
+	}
+}
+
+func TestMarkdownV2ShouldSeparateItalicAndUnderline(t *testing.T) {
+	// Act
+	msg := format.MarkdownV2(format.Underline(format.Italic("x")))
+
+	// Assert
+	expected := "__\r_x_\r__"
+	if msg.Text != expected {
+		t.Errorf("Expected %q, found %q", expected, msg.Text)
+	}
+}
+
+func TestEntitiesShouldUseUTF16Offsets(t *testing.T) {
+	// Act
+	msg := format.New().Text("😀 ").Bold("hi ", format.Link("https://x.y", "go")).Text(" ").Mention(7, "me").Bold("").Entities()
+
+	// Assert
+	if msg.Text != "😀 hi go me" {
+		t.Errorf("Expected plain text, found %q", msg.Text)
+	}
+	if msg.ParseMode != "" {
+		t.Errorf("Expected no parse mode, found %q", msg.ParseMode)
+	}
+
+	expected := []tgbotapi.MessageEntity{
+		{Type: "bold", Offset: 3, Length: 5},
+		{Type: "text_link", Offset: 6, Length: 2, URL: "https://x.y"},
+		{Type: "text_mention", Offset: 9, Length: 2, User: &tgbotapi.User{ID: 7}},
+	}
+	if len(msg.Entities) != len(expected) {
+		t.Fatalf("Expected %d entities, found %+v", len(expected), msg.Entities)
+	}
+	for i, e := range expected {
+		got := msg.Entities[i]
+		if got.Type != e.Type || got.Offset != e.Offset || got.Length != e.Length || got.URL != e.URL {
+			t.Errorf("Expected entity %+v, found %+v", e, got)
+		}
+	}
+	if msg.Entities[2].User == nil || msg.Entities[2].User.ID != 7 {
+		t.Errorf("Expected mentioned user, found %+v", msg.Entities[2].User)
+	}
+}
+
+func TestPlainShouldFormatValues(t *testing.T) {
+	// Act
+	msg := format.HTML(format.Plain("n=", 3, " ", true))
+
+	// Assert
+	if msg.Text != "n=3 true" {
+		t.Errorf("Expected %q, found %q", "n=3 true", msg.Text)
+	}
+}
+
+func TestValidateShouldReportInvalidMarkup(t *testing.T) {
+	// Arrange
+	cases := []struct {
+		text, parseMode string
+		valid           bool
+	}{
+		{`<b>a &amp; <a href="x">b</a></b> &#33;`, tgbotapi.ModeHTML, true},
+		{"<b>a</i>", tgbotapi.ModeHTML, false},
+		{"<div>a</div>", tgbotapi.ModeHTML, false},
+		{"a & b", tgbotapi.ModeHTML, false},
+		{"*a* _b_ ||c|| [d\\.](https://x.y/(1\\)) `e.` \\.\n>quote", tgbotapi.ModeMarkdownV2, true},
+		{"a.b", tgbotapi.ModeMarkdownV2, false},
+		{"*a", tgbotapi.ModeMarkdownV2, false},
+		{"a.b <i>", "", true},
+	}
+
+	for _, c := range cases {
+		// Act
+		err := format.Validate(c.text, c.parseMode)
+
+		// Assert
+		if (err == nil) != c.valid {
+			t.Errorf("Expected %q valid = %v, found %v", c.text, c.valid, err)
+		}
+	}
+}
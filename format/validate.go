@@ -0,0 +1,188 @@
+package format
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Tags accepted by Telegram with parse mode HTML.
+var validHTMLTags = map[string]bool{
+	"b": true, "strong": true, "i": true, "em": true, "u": true, "ins": true,
+	"s": true, "strike": true, "del": true, "span": true, "tg-spoiler": true,
+	"a": true, "code": true, "pre": true, "tg-emoji": true, "blockquote": true,
+}
+
+// Validate reports markup that Telegram would reject in text sent with
+// parseMode: unknown or unbalanced tags and bare "<", ">" or "&" for HTML,
+// unbalanced entities and unescaped reserved characters for MarkdownV2.
+// Other parse modes are not checked.
+func Validate(text, parseMode string) error {
+	switch parseMode {
+	case tgbotapi.ModeHTML:
+		return validateHTML(text)
+	case tgbotapi.ModeMarkdownV2:
+		return validateMarkdownV2(text)
+	}
+	return nil
+}
+
+func validateHTML(text string) error {
+	var open []string
+
+	for i := 0; i < len(text); {
+		switch text[i] {
+		case '<':
+			end := strings.IndexByte(text[i:], '>')
+			if end < 0 {
+				return fmt.Errorf("format: unterminated tag at byte %d", i)
+			}
+
+			tag := text[i+1 : i+end]
+			closing := strings.HasPrefix(tag, "/")
+			fields := strings.Fields(strings.TrimPrefix(tag, "/"))
+			if len(fields) == 0 || !validHTMLTags[strings.ToLower(fields[0])] {
+				return fmt.Errorf("format: unsupported tag <%s> at byte %d", tag, i)
+			}
+
+			name := strings.ToLower(fields[0])
+			if !closing {
+				open = append(open, name)
+			} else if len(open) == 0 || open[len(open)-1] != name {
+				return fmt.Errorf("format: unexpected </%s> at byte %d", name, i)
+			} else {
+				open = open[:len(open)-1]
+			}
+			i += end + 1
+
+		case '>':
+			return fmt.Errorf("format: unescaped \">\" at byte %d", i)
+
+		case '&':
+			end := strings.IndexByte(text[i:], ';')
+			if end < 0 || !isHTMLEntity(text[i+1:i+end]) {
+				return fmt.Errorf("format: unescaped \"&\" at byte %d", i)
+			}
+			i += end + 1
+
+		default:
+			i++
+		}
+	}
+
+	if len(open) > 0 {
+		return fmt.Errorf("format: unclosed <%s>", open[len(open)-1])
+	}
+	return nil
+}
+
+// Telegram supports the named entities &lt; &gt; &amp; &quot; and numeric ones.
+func isHTMLEntity(name string) bool {
+	switch name {
+	case "lt", "gt", "amp", "quot":
+		return true
+	}
+
+	digits, ok := strings.CutPrefix(name, "#")
+	if !ok || digits == "" {
+		return false
+	}
+	if hex, ok := strings.CutPrefix(strings.ToLower(digits), "x"); ok {
+		return hex != "" && strings.Trim(hex, "0123456789abcdef") == ""
+	}
+	return strings.Trim(digits, "0123456789") == ""
+}
+
+// Characters that must be escaped with MarkdownV2 when they are not markup.
+const markdownReserved = "_*[]()~`>#+-=|{}.!"
+
+func validateMarkdownV2(text string) error {
+	open := map[string]bool{}
+	code, pre := false, false
+
+	for i := 0; i < len(text); {
+		rest := text[i:]
+
+		if rest[0] == '\\' {
+			if len(rest) < 2 {
+				return fmt.Errorf("format: trailing \"\\\" at byte %d", i)
+			}
+			_, size := utf8.DecodeRuneInString(rest[1:])
+			i += 1 + size
+			continue
+		}
+
+		switch {
+		case pre:
+			if strings.HasPrefix(rest, "```") {
+				pre = false
+				i += 3
+				continue
+			}
+		case code:
+			code = rest[0] != '`'
+		case strings.HasPrefix(rest, "```"):
+			pre = true
+			i += 3
+			continue
+		case rest[0] == '`':
+			code = true
+		case strings.HasPrefix(rest, "||"), strings.HasPrefix(rest, "__"):
+			open[rest[:2]] = !open[rest[:2]]
+			i += 2
+			continue
+		case rest[0] == '*', rest[0] == '_', rest[0] == '~':
+			open[rest[:1]] = !open[rest[:1]]
+		case rest[0] == '[', strings.HasPrefix(rest, "!["):
+			// The link text may hold entities; the URL is skipped.
+			end := strings.Index(rest, "](")
+			if end < 0 {
+				return fmt.Errorf("format: unescaped %q at byte %d", rest[0], i)
+			}
+			urlEnd := markdownURLEnd(rest, end+2)
+			if urlEnd < 0 {
+				return fmt.Errorf("format: unterminated link at byte %d", i)
+			}
+			if err := validateMarkdownV2(strings.TrimPrefix(rest[1:end], "[")); err != nil {
+				return err
+			}
+			i += urlEnd + 1
+			continue
+		case rest[0] == '>' && (i == 0 || text[i-1] == '\n'):
+			// Block quotation.
+		case strings.IndexByte(markdownReserved, rest[0]) >= 0:
+			return fmt.Errorf("format: unescaped %q at byte %d", rest[0], i)
+		}
+
+		_, size := utf8.DecodeRuneInString(rest)
+		i += size
+	}
+
+	switch {
+	case pre:
+		return fmt.Errorf("format: unclosed pre block")
+	case code:
+		return fmt.Errorf("format: unclosed code")
+	}
+	for marker, isOpen := range open {
+		if isOpen {
+			return fmt.Errorf("format: unclosed %q", marker)
+		}
+	}
+	return nil
+}
+
+// Return the index of the ")" closing the URL that starts at from in s, or -1.
+func markdownURLEnd(s string, from int) int {
+	for i := from; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case ')':
+			return i
+		}
+	}
+	return -1
+}
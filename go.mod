@@ -5,5 +5,4 @@ go 1.24.4
 require (
 	github.com/go-telegram-bot-api/telegram-bot-api/v5 v5.5.1
 	github.com/google/uuid v1.6.0
-	github.com/joho/godotenv v1.5.1
 )
@@ -1,10 +1,10 @@
 package tgbotapp
 
 import (
-	"errors"
 	"log/slog"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nexoratech2025/go-telegram-bot-app/format"
 	"github.com/nexoratech2025/go-telegram-bot-app/session"
 )
 
@@ -46,60 +46,120 @@ func (h *HandlerContext) GetParams() HandlerParams {
 	}
 }
 
-func (h *HandlerContext) SendMessage(text string, parseMode ...string) error {
-	msg := tgbotapi.NewMessage(h.Update.FromChat().ChatConfig().ChatID, text)
+// Send a text message with an optional parse mode. See SendText for more
+// options.
+func (h *HandlerContext) SendMessage(text string, parseMode ...string) ([]tgbotapi.Message, error) {
+	var opts []SendOption
+	if len(parseMode) > 0 {
+		opts = append(opts, ParseMode(parseMode[0]))
+	}
+	return h.SendText(text, opts...)
+}
 
-	if len(parseMode) > 0 && parseMode[0] != "" {
-		mode := parseMode[0]
-		validModes := []string{ParseModeHTML, ParseModeMarkdown, ParseModeMarkdownV2}
-		isValid := false
-		for _, validMode := range validModes {
-			if mode == validMode {
-				isValid = true
-				break
-			}
-		}
+// Send a text message and return the sent messages, more than one if the
+// text was split. See SendOption for the available options.
+func (h *HandlerContext) SendText(text string, opts ...SendOption) ([]tgbotapi.Message, error) {
+	return h.sendText(tgbotapi.NewMessage(h.GetChatID(), text), newSendOptions(opts))
+}
 
-		if !isValid {
-			h.Logger.ErrorContext(h.Ctx, "Invalid parse mode", "parseMode", mode)
-			h.SendError("Invalid parse mode provided")
-			return errors.New("invalid parse mode provided")
-		}
+func (h *HandlerContext) SendMessageWithKeyboard(text string, keyboard interface{}, opts ...SendOption) ([]tgbotapi.Message, error) {
+	return h.SendText(text, append(opts, Keyboard(keyboard))...)
+}
 
-		msg.ParseMode = mode
+func (h *HandlerContext) SendMessageWithInlineKeyboard(text string, keyboard tgbotapi.InlineKeyboardMarkup, opts ...SendOption) ([]tgbotapi.Message, error) {
+	return h.SendText(text, append(opts, Keyboard(keyboard))...)
+}
+
+// Send text built with the format package. The parse mode of text wins over
+// the ParseMode option.
+func (h *HandlerContext) SendFormatted(text format.Message, opts ...SendOption) ([]tgbotapi.Message, error) {
+	o := newSendOptions(opts)
+	o.parseMode = text.ParseMode
+	return h.sendText(formattedMessage(h.GetChatID(), text), o)
+}
+
+func (h *HandlerContext) SendFormattedWithKeyboard(text format.Message, keyboard interface{}, opts ...SendOption) ([]tgbotapi.Message, error) {
+	return h.SendFormatted(text, append(opts, Keyboard(keyboard))...)
+}
+
+// Send the application's message template name rendered with data.
+func (h *HandlerContext) SendTemplate(name string, data any, opts ...SendOption) ([]tgbotapi.Message, error) {
+	if h.app == nil || h.app.Templates == nil {
+		h.LogError("Cannot send template.", ErrNoTemplates)
+		return nil, ErrNoTemplates
 	}
 
-	_, err := h.BotAPI.Send(msg)
+	text, err := h.app.Templates.Execute(name, data)
 	if err != nil {
-		h.HandleSendMessageError(err)
-		return err
+		h.LogError("Cannot render template.", err)
+		return nil, err
 	}
 
-	return nil
+	return h.SendFormatted(text, opts...)
 }
 
-func (h *HandlerContext) SendMessageWithKeyboard(text string, keyboard interface{}) error {
-	msg := tgbotapi.NewMessage(h.Update.FromChat().ChatConfig().ChatID, text)
-	msg.ReplyMarkup = keyboard
-	_, err := h.BotAPI.Send(msg)
-	if err != nil {
-		h.HandleSendMessageError(err)
-		return err
-	}
+func (h *HandlerContext) SendTemplateWithKeyboard(name string, data any, keyboard interface{}, opts ...SendOption) ([]tgbotapi.Message, error) {
+	return h.SendTemplate(name, data, append(opts, Keyboard(keyboard))...)
+}
 
-	return nil
+func formattedMessage(chatID int64, text format.Message) tgbotapi.MessageConfig {
+	msg := tgbotapi.NewMessage(chatID, text.Text)
+	msg.ParseMode = text.ParseMode
+	msg.Entities = text.Entities
+	return msg
 }
 
-func (h *HandlerContext) SendMessageWithInlineKeyboard(text string, keyboard tgbotapi.InlineKeyboardMarkup) error {
-	msg := tgbotapi.NewMessage(h.Update.FromChat().ChatConfig().ChatID, text)
-	msg.ReplyMarkup = keyboard
-	_, err := h.BotAPI.Send(msg)
-	if err != nil {
-		h.HandleSendMessageError(err)
-		return err
+// Send msg, split into several messages if the application splits long
+// messages. The reply markup is only attached to the last one, and the
+// message replied to only to the first one.
+func (h *HandlerContext) sendText(msg tgbotapi.MessageConfig, o *sendOptions) ([]tgbotapi.Message, error) {
+	if err := o.validate(); err != nil {
+		h.LogError("Cannot send message.", err)
+		return nil, err
 	}
 
-	return nil
+	msg = o.apply(msg).(tgbotapi.MessageConfig)
+
+	chunks := []format.Message{{Text: msg.Text, Entities: msg.Entities}}
+	if h.app != nil && h.app.SplitLimit > 0 {
+		if len(msg.Entities) > 0 {
+			chunks = SplitEntities(msg.Text, msg.Entities, h.app.SplitLimit)
+		} else {
+			chunks = nil
+			for _, text := range SplitMessage(msg.Text, msg.ParseMode, h.app.SplitLimit) {
+				chunks = append(chunks, format.Message{Text: text})
+			}
+		}
+	}
+
+	markup, replyTo := msg.ReplyMarkup, msg.ReplyToMessageID
+	sent := make([]tgbotapi.Message, 0, len(chunks))
+	for i, chunk := range chunks {
+		msg.Text, msg.Entities = chunk.Text, chunk.Entities
+		msg.ReplyMarkup, msg.ReplyToMessageID = nil, 0
+		if i == 0 {
+			msg.ReplyToMessageID = replyTo
+		}
+		if i == len(chunks)-1 {
+			msg.ReplyMarkup = markup
+		}
+
+		res, err := h.sendWith(msg, o)
+		if err != nil {
+			h.LogError("Cannot send message.", err)
+			return sent, err
+		}
+		sent = append(sent, res)
+	}
+
+	return sent, nil
+}
+
+// Send c to the current chat with the options that tgbotapi cannot express.
+// The other options must already be applied.
+func (h *HandlerContext) sendWith(c tgbotapi.Chattable, o *sendOptions) (res tgbotapi.Message, err error) {
+	err = h.requestResult(withExtraParams(h.Ctx, o.extra(c)), h.GetChatID(), c, &res)
+	return
 }
 
 func (h *HandlerContext) SendError(message string) {
@@ -115,6 +175,47 @@ func (h *HandlerContext) SetState(state string) {
 	h.Session.SetState(state)
 }
 
+// Push state on top of the session's state stack. Falls back to SetState for
+// sessions without a state stack.
+func (h *HandlerContext) PushState(state string) {
+	if st, ok := h.Session.(session.StateStack); ok {
+		st.Push(state)
+		return
+	}
+	h.Session.SetState(state)
+}
+
+// Pop the current state and return the one below it.
+func (h *HandlerContext) PopState() (string, bool) {
+	if st, ok := h.Session.(session.StateStack); ok {
+		return st.Pop()
+	}
+	return "", false
+}
+
+func (h *HandlerContext) ResetState() {
+	if st, ok := h.Session.(session.StateStack); ok {
+		st.Reset()
+		return
+	}
+	h.Session.SetState("")
+}
+
+// Get scratch data of the current state stack frame.
+func (h *HandlerContext) GetFrameData(key string) (any, bool) {
+	if st, ok := h.Session.(session.StateStack); ok {
+		return st.FrameGet(key)
+	}
+	return nil, false
+}
+
+// Set scratch data on the current state stack frame. It is discarded when the frame is popped.
+func (h *HandlerContext) SetFrameData(key string, value any) {
+	if st, ok := h.Session.(session.StateStack); ok {
+		st.FrameSet(key, value)
+	}
+}
+
 func (h *HandlerContext) GetSessionData(key string) (interface{}, bool) {
 	return h.Session.Get(key)
 }
@@ -127,6 +228,16 @@ func (h *HandlerContext) ClearSessionData() {
 	h.Session.ClearData()
 }
 
+// Clear the session and delete it from the session manager once the handler returns.
+func (h *HandlerContext) DestroySession() {
+	if d, ok := h.Session.(session.Destroyer); ok {
+		d.Destroy()
+		return
+	}
+	h.Session.ClearData()
+	h.Session.SetState("")
+}
+
 func (h *HandlerContext) HasDocument() bool {
 	return hasDocument(h.Update.Message)
 }
@@ -218,170 +329,132 @@ func (h *HandlerContext) GetCallbackQuery() *tgbotapi.CallbackQuery {
 func (h *HandlerContext) AnswerCallbackQuery(text string) {
 	if h.Update.CallbackQuery != nil {
 		callback := tgbotapi.NewCallback(h.Update.CallbackQuery.ID, text)
-		h.BotAPI.Send(callback)
+		if _, err := h.request(0, callback); err != nil {
+			h.LogError("Failed to answer callback query", err)
+		}
 	}
 }
 
 func (h *HandlerContext) AnswerCallbackQueryWithAlert(text string) {
 	if h.Update.CallbackQuery != nil {
 		callback := tgbotapi.NewCallbackWithAlert(h.Update.CallbackQuery.ID, text)
-		h.BotAPI.Send(callback)
+		if _, err := h.request(0, callback); err != nil {
+			h.LogError("Failed to answer callback query", err)
+		}
 	}
 }
 
-func (h *HandlerContext) SendPhoto(photo tgbotapi.FileBytes, caption string) error {
-	msg := tgbotapi.NewPhoto(h.Update.FromChat().ChatConfig().ChatID, photo)
-	if caption != "" {
-		msg.Caption = caption
-	}
-	_, err := h.BotAPI.Send(msg)
-	if err != nil {
-		h.HandleSendMessageError(err)
-		return err
-	}
-
-	return nil
+// Send a photo from any file source: tgbotapi.FileID to reuse a file already
+// on Telegram's servers, FileURL, FilePath, FileReader or FileBytes. The
+// other file helpers accept the same sources.
+func (h *HandlerContext) SendPhoto(photo tgbotapi.RequestFileData, caption string, opts ...SendOption) (tgbotapi.Message, error) {
+	msg := tgbotapi.NewPhoto(h.GetChatID(), photo)
+	msg.Caption = caption
+	return h.sendChattable(msg, opts)
 }
 
-func (h *HandlerContext) SendDocument(document tgbotapi.FileBytes, caption string) error {
-	msg := tgbotapi.NewDocument(h.Update.FromChat().ChatConfig().ChatID, document)
-	if caption != "" {
-		msg.Caption = caption
-	}
-	_, err := h.BotAPI.Send(msg)
-	if err != nil {
-		h.HandleSendMessageError(err)
-		return err
-	}
-
-	return nil
+func (h *HandlerContext) SendDocument(document tgbotapi.RequestFileData, caption string, opts ...SendOption) (tgbotapi.Message, error) {
+	msg := tgbotapi.NewDocument(h.GetChatID(), document)
+	msg.Caption = caption
+	return h.sendChattable(msg, opts)
 }
 
-func (h *HandlerContext) SendVideo(video tgbotapi.FileBytes, caption string) error {
-	msg := tgbotapi.NewVideo(h.Update.FromChat().ChatConfig().ChatID, video)
-	if caption != "" {
-		msg.Caption = caption
-	}
-	_, err := h.BotAPI.Send(msg)
-	if err != nil {
-		h.HandleSendMessageError(err)
-		return err
-	}
-
-	return nil
+func (h *HandlerContext) SendVideo(video tgbotapi.RequestFileData, caption string, opts ...SendOption) (tgbotapi.Message, error) {
+	msg := tgbotapi.NewVideo(h.GetChatID(), video)
+	msg.Caption = caption
+	return h.sendChattable(msg, opts)
 }
 
-func (h *HandlerContext) SendAudio(audio tgbotapi.FileBytes, caption string) error {
-	msg := tgbotapi.NewAudio(h.Update.FromChat().ChatConfig().ChatID, audio)
-	if caption != "" {
-		msg.Caption = caption
-	}
-	_, err := h.BotAPI.Send(msg)
-	if err != nil {
-		h.HandleSendMessageError(err)
-		return err
-	}
+func (h *HandlerContext) SendAnimation(animation tgbotapi.RequestFileData, caption string, opts ...SendOption) (tgbotapi.Message, error) {
+	msg := tgbotapi.NewAnimation(h.GetChatID(), animation)
+	msg.Caption = caption
+	return h.sendChattable(msg, opts)
+}
 
-	return nil
+func (h *HandlerContext) SendAudio(audio tgbotapi.RequestFileData, caption string, opts ...SendOption) (tgbotapi.Message, error) {
+	msg := tgbotapi.NewAudio(h.GetChatID(), audio)
+	msg.Caption = caption
+	return h.sendChattable(msg, opts)
 }
 
-func (h *HandlerContext) SendVoice(voice tgbotapi.FileBytes) error {
-	msg := tgbotapi.NewVoice(h.Update.FromChat().ChatConfig().ChatID, voice)
-	_, err := h.BotAPI.Send(msg)
-	if err != nil {
-		h.HandleSendMessageError(err)
-		return err
-	}
+func (h *HandlerContext) SendVoice(voice tgbotapi.RequestFileData, opts ...SendOption) (tgbotapi.Message, error) {
+	return h.sendChattable(tgbotapi.NewVoice(h.GetChatID(), voice), opts)
+}
 
-	return nil
+func (h *HandlerContext) SendVideoNote(videoNote tgbotapi.RequestFileData, length int, opts ...SendOption) (tgbotapi.Message, error) {
+	return h.sendChattable(tgbotapi.NewVideoNote(h.GetChatID(), length, videoNote), opts)
 }
 
-func (h *HandlerContext) SendSticker(sticker tgbotapi.FileBytes) error {
-	msg := tgbotapi.NewSticker(h.Update.FromChat().ChatConfig().ChatID, sticker)
-	_, err := h.BotAPI.Send(msg)
-	if err != nil {
-		h.HandleSendMessageError(err)
-		return err
-	}
+func (h *HandlerContext) SendSticker(sticker tgbotapi.RequestFileData, opts ...SendOption) (tgbotapi.Message, error) {
+	return h.sendChattable(tgbotapi.NewSticker(h.GetChatID(), sticker), opts)
+}
 
-	return nil
+func (h *HandlerContext) sendChattable(c tgbotapi.Chattable, opts []SendOption) (tgbotapi.Message, error) {
+	return h.sendChattableWith(c, newSendOptions(opts))
 }
 
-func (h *HandlerContext) SendLocation(latitude, longitude float64) error {
-	msg := tgbotapi.NewLocation(h.Update.FromChat().ChatConfig().ChatID, latitude, longitude)
-	_, err := h.BotAPI.Send(msg)
-	if err != nil {
-		h.HandleSendMessageError(err)
-		return err
+func (h *HandlerContext) sendChattableWith(c tgbotapi.Chattable, o *sendOptions) (tgbotapi.Message, error) {
+	if err := o.validate(); err != nil {
+		h.LogError("Cannot send message.", err)
+		return tgbotapi.Message{}, err
 	}
 
-	return nil
-}
-
-func (h *HandlerContext) SendVenue(latitude, longitude float64, title, address string) error {
-	msg := tgbotapi.NewVenue(h.Update.FromChat().ChatConfig().ChatID, title, address, latitude, longitude)
-	_, err := h.BotAPI.Send(msg)
+	msg, err := h.sendWith(o.apply(c), o)
 	if err != nil {
-		h.HandleSendMessageError(err)
-		return err
+		h.LogError("Cannot send message.", err)
+		return msg, err
 	}
 
-	return nil
+	return msg, nil
 }
 
-func (h *HandlerContext) SendContact(phoneNumber, firstName string) error {
-	msg := tgbotapi.NewContact(h.Update.FromChat().ChatConfig().ChatID, phoneNumber, firstName)
-	_, err := h.BotAPI.Send(msg)
-	if err != nil {
-		h.HandleSendMessageError(err)
-		return err
-	}
+func (h *HandlerContext) SendLocation(latitude, longitude float64, opts ...SendOption) (tgbotapi.Message, error) {
+	return h.sendChattable(tgbotapi.NewLocation(h.GetChatID(), latitude, longitude), opts)
+}
 
-	return nil
+func (h *HandlerContext) SendVenue(latitude, longitude float64, title, address string, opts ...SendOption) (tgbotapi.Message, error) {
+	return h.sendChattable(tgbotapi.NewVenue(h.GetChatID(), title, address, latitude, longitude), opts)
 }
 
-func (h *HandlerContext) SendPoll(question string, options []string) error {
-	msg := tgbotapi.NewPoll(h.Update.FromChat().ChatConfig().ChatID, question, options...)
-	_, err := h.BotAPI.Send(msg)
-	if err != nil {
-		h.HandleSendMessageError(err)
-		return err
-	}
+func (h *HandlerContext) SendContact(phoneNumber, firstName string, opts ...SendOption) (tgbotapi.Message, error) {
+	return h.sendChattable(tgbotapi.NewContact(h.GetChatID(), phoneNumber, firstName), opts)
+}
 
-	return nil
+func (h *HandlerContext) SendPoll(question string, options []string, opts ...SendOption) (tgbotapi.Message, error) {
+	return h.sendChattable(tgbotapi.NewPoll(h.GetChatID(), question, options...), opts)
 }
 
 func (h *HandlerContext) DeleteMessage(messageID int) error {
 	deleteMsg := tgbotapi.NewDeleteMessage(h.Update.FromChat().ChatConfig().ChatID, messageID)
-	_, err := h.BotAPI.Send(deleteMsg)
+	_, err := h.request(h.GetChatID(), deleteMsg)
 	if err != nil {
-		h.HandleSendMessageError(err)
+		h.LogError("Cannot delete message.", err)
 		return err
 	}
 
 	return nil
 }
 
-func (h *HandlerContext) EditMessageText(text string, messageID int) error {
-	editMsg := tgbotapi.NewEditMessageText(h.Update.FromChat().ChatConfig().ChatID, messageID, text)
-	_, err := h.BotAPI.Send(editMsg)
-	if err != nil {
-		h.HandleSendMessageError(err)
-		return err
-	}
-
-	return nil
+func (h *HandlerContext) EditMessageText(text string, messageID int, opts ...SendOption) error {
+	_, err := h.sendChattable(tgbotapi.NewEditMessageText(h.GetChatID(), messageID, text), opts)
+	return err
 }
 
-func (h *HandlerContext) EditMessageReplyMarkup(replyMarkup tgbotapi.InlineKeyboardMarkup, messageID int) error {
-	editMsg := tgbotapi.NewEditMessageReplyMarkup(h.Update.FromChat().ChatConfig().ChatID, messageID, replyMarkup)
-	_, err := h.BotAPI.Send(editMsg)
-	if err != nil {
-		h.HandleSendMessageError(err)
-		return err
-	}
+// Replace the text of a message with text built with the format package.
+// The parse mode of text wins over the ParseMode option.
+func (h *HandlerContext) EditFormatted(text format.Message, messageID int, opts ...SendOption) error {
+	editMsg := tgbotapi.NewEditMessageText(h.GetChatID(), messageID, text.Text)
+	editMsg.Entities = text.Entities
 
-	return nil
+	o := newSendOptions(opts)
+	o.parseMode = text.ParseMode
+	_, err := h.sendChattableWith(editMsg, o)
+	return err
+}
+
+func (h *HandlerContext) EditMessageReplyMarkup(replyMarkup tgbotapi.InlineKeyboardMarkup, messageID int, opts ...SendOption) error {
+	_, err := h.sendChattable(tgbotapi.NewEditMessageReplyMarkup(h.GetChatID(), messageID, replyMarkup), opts)
+	return err
 }
 
 func (h *HandlerContext) GetChatID() int64 {
@@ -0,0 +1,125 @@
+package tgbotapp
+
+import (
+	"errors"
+	"slices"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nexoratech2025/go-telegram-bot-app/i18n"
+)
+
+const (
+	// Session data key of the locale chosen by the user.
+	LocaleSessionKey = "locale"
+	// Prefix of the catalog keys of command descriptions.
+	CommandKeyPrefix = "commands."
+)
+
+var ErrNoSession = errors.New("Handler has no session.")
+
+// Translate messages with b. See HandlerContext.T.
+func WithI18n(b *i18n.Bundle) OptionFunc {
+	return func(a *Application) {
+		a.I18n = b
+	}
+}
+
+// Locale of the user: the locale stored in the session by SetLocale, or the
+// language of the user's Telegram client, matched against the application's
+// catalogs.
+func (h *HandlerContext) Locale() string {
+	var tags []string
+
+	if h.Session != nil {
+		if l, ok := h.Session.Get(LocaleSessionKey); ok {
+			if s, ok := l.(string); ok && s != "" {
+				tags = append(tags, s)
+			}
+		}
+	}
+
+	if user := h.Update.SentFrom(); user != nil && user.LanguageCode != "" {
+		tags = append(tags, user.LanguageCode)
+	}
+
+	if h.app == nil || h.app.I18n == nil {
+		if len(tags) > 0 {
+			return i18n.Normalize(tags[0])
+		}
+		return ""
+	}
+
+	return h.app.I18n.Match(tags...)
+}
+
+// Override the user's locale for the rest of the session. ErrNoSession is
+// returned if the handler runs without a session.
+func (h *HandlerContext) SetLocale(locale string) error {
+	if h.Session == nil {
+		return ErrNoSession
+	}
+	h.Session.Set(LocaleSessionKey, i18n.Normalize(locale))
+	return nil
+}
+
+// Translate key into the user's locale. args are alternating placeholder
+// names and values, such as T("apples", "count", 3). The key is returned if
+// the application has no catalogs.
+func (h *HandlerContext) T(key string, args ...any) string {
+	if h.app == nil || h.app.I18n == nil {
+		return key
+	}
+	return h.app.I18n.T(h.Locale(), key, args...)
+}
+
+// Commands with descriptions translated into locale, or into the default
+// locale if locale is empty.
+func (a *Application) localizedCommands(locale string) []tgbotapi.BotCommand {
+	cmds := slices.Clone(botCommands)
+	if a.I18n == nil {
+		return cmds
+	}
+
+	if locale == "" {
+		locale = a.I18n.DefaultLocale()
+	}
+
+	for i, cmd := range cmds {
+		key := CommandKeyPrefix + cmd.Command
+		if desc := a.I18n.T(locale, key); desc != key {
+			cmds[i].Description = desc
+		}
+	}
+
+	return cmds
+}
+
+// Locales of languages other than the default one that translate any
+// command, at most one per language. Telegram only accepts two-letter
+// language codes.
+func (a *Application) commandLocales() []string {
+	if a.I18n == nil {
+		return nil
+	}
+
+	def := i18n.Base(a.I18n.DefaultLocale())
+	var locales, langs []string
+
+	// Locales are sorted, so a language comes before its regional variants.
+	for _, locale := range a.I18n.Locales() {
+		lang := i18n.Base(locale)
+		if lang == def || len(lang) != 2 || slices.Contains(langs, lang) {
+			continue
+		}
+
+		for _, cmd := range botCommands {
+			if a.I18n.Has(locale, CommandKeyPrefix+cmd.Command) {
+				locales = append(locales, locale)
+				langs = append(langs, lang)
+				break
+			}
+		}
+	}
+
+	return locales
+}
@@ -0,0 +1,377 @@
+// Package i18n provides message catalogs with CLDR plural rules and locale
+// fallbacks.
+//
+// Catalogs map keys to messages. A message is either a string or an object
+// with plural forms; other objects are namespaces whose keys are joined with
+// dots:
+//
+//	{
+//	  "greeting": "Hello, {name}!",
+//	  "apples": {"one": "{count} apple", "other": "{count} apples"},
+//	  "commands": {"start": "Start the bot"}
+//	}
+//
+// Placeholders in braces are replaced by the arguments of the same name. The
+// argument "count" selects the plural form.
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Argument selecting the plural form of a message.
+const CountArg = "count"
+
+// UnmarshalFunc decodes a catalog file, like json.Unmarshal.
+type UnmarshalFunc func(data []byte, v any) error
+
+type message struct {
+	text   string
+	plural map[string]string
+}
+
+// Bundle holds the catalogs of all locales.
+type Bundle struct {
+	mu            sync.RWMutex
+	defaultLocale string
+	catalogs      map[string]map[string]message
+	rules         map[string]PluralRule
+	formats       map[string]UnmarshalFunc
+}
+
+// NewBundle returns an empty bundle. Lookups that find no message in the
+// requested locale fall back to defaultLocale.
+func NewBundle(defaultLocale string) *Bundle {
+	return &Bundle{
+		defaultLocale: Normalize(defaultLocale),
+		catalogs:      make(map[string]map[string]message),
+		rules:         make(map[string]PluralRule),
+		formats:       map[string]UnmarshalFunc{".json": json.Unmarshal},
+	}
+}
+
+func (b *Bundle) DefaultLocale() string {
+	return b.defaultLocale
+}
+
+// RegisterFormat makes LoadFS decode files with extension ext using
+// unmarshal. JSON is registered by default; register YAML with the
+// Unmarshal function of a YAML package for ".yaml" and ".yml".
+func (b *Bundle) RegisterFormat(ext string, unmarshal UnmarshalFunc) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.formats[ext] = unmarshal
+}
+
+// SetPluralRule sets the plural rule of a language, replacing the built-in
+// rule if there is one.
+func (b *Bundle) SetPluralRule(lang string, rule PluralRule) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rules[Normalize(lang)] = rule
+}
+
+// LoadFS adds the catalog files in fsys matching patterns, *.json by
+// default. Files are named after their locale, such as en.json or
+// pt-BR.yaml.
+func (b *Bundle) LoadFS(fsys fs.FS, patterns ...string) error {
+	if len(patterns) == 0 {
+		patterns = []string{"*.json"}
+	}
+
+	for _, pattern := range patterns {
+		files, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			if err = b.loadFile(fsys, file); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (b *Bundle) loadFile(fsys fs.FS, file string) error {
+	ext := path.Ext(file)
+
+	b.mu.RLock()
+	unmarshal, ok := b.formats[ext]
+	b.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("i18n: %s: no format registered for %q", file, ext)
+	}
+
+	data, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return err
+	}
+
+	var messages map[string]any
+	if err = unmarshal(data, &messages); err != nil {
+		return fmt.Errorf("i18n: %s: %w", file, err)
+	}
+
+	if err = b.AddMessages(strings.TrimSuffix(path.Base(file), ext), messages); err != nil {
+		return fmt.Errorf("i18n: %s: %w", file, err)
+	}
+
+	return nil
+}
+
+// AddMessages adds a decoded catalog to locale. Existing keys are replaced.
+func (b *Bundle) AddMessages(locale string, messages map[string]any) error {
+	flat := make(map[string]message)
+	if err := flatten(flat, "", messages); err != nil {
+		return err
+	}
+
+	locale = Normalize(locale)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	catalog := b.catalogs[locale]
+	if catalog == nil {
+		catalog = make(map[string]message)
+		b.catalogs[locale] = catalog
+	}
+	for k, m := range flat {
+		catalog[k] = m
+	}
+
+	return nil
+}
+
+func flatten(out map[string]message, prefix string, messages map[string]any) error {
+	for k, v := range messages {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+
+		switch v := v.(type) {
+		case string:
+			out[key] = message{text: v}
+
+		case map[string]any:
+			if plural, ok := pluralForms(v); ok {
+				out[key] = message{plural: plural}
+				continue
+			}
+			if err := flatten(out, key, v); err != nil {
+				return err
+			}
+
+		case map[any]any:
+			m := make(map[string]any, len(v))
+			for mk, mv := range v {
+				m[fmt.Sprint(mk)] = mv
+			}
+			if err := flatten(out, prefix, map[string]any{k: m}); err != nil {
+				return err
+			}
+
+		default:
+			return fmt.Errorf("message %q: unsupported value %T", key, v)
+		}
+	}
+
+	return nil
+}
+
+// Return the plural forms of m if all its keys are plural categories.
+func pluralForms(m map[string]any) (map[string]string, bool) {
+	if _, ok := m[Other]; !ok {
+		return nil, false
+	}
+
+	forms := make(map[string]string, len(m))
+	for k, v := range m {
+		s, ok := v.(string)
+		if !ok || !isPluralCategory(k) {
+			return nil, false
+		}
+		forms[k] = s
+	}
+
+	return forms, true
+}
+
+// Locales returns the locales that have a catalog.
+func (b *Bundle) Locales() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	locales := make([]string, 0, len(b.catalogs))
+	for l := range b.catalogs {
+		locales = append(locales, l)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Has reports whether locale itself, without fallbacks, has a message for key.
+func (b *Bundle) Has(locale, key string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	_, ok := b.catalogs[Normalize(locale)][key]
+	return ok
+}
+
+// Match returns the first of the given language tags that has a catalog,
+// trying each tag's base language as well, or the default locale.
+func (b *Bundle) Match(tags ...string) string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, tag := range tags {
+		for _, l := range fallbacks(Normalize(tag)) {
+			if _, ok := b.catalogs[l]; ok {
+				return l
+			}
+		}
+	}
+
+	return b.defaultLocale
+}
+
+// T returns the message key in locale with its placeholders replaced. args
+// are alternating names and values, or a single map[string]any. If no locale
+// in the fallback chain (locale, its base language, the default locale) has
+// the message, key is returned.
+func (b *Bundle) T(locale, key string, args ...any) string {
+	named := namedArgs(args)
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	chain := append(fallbacks(Normalize(locale)), b.defaultLocale)
+	for _, l := range chain {
+		m, ok := b.catalogs[l][key]
+		if !ok {
+			continue
+		}
+
+		text := m.text
+		if m.plural != nil {
+			text = b.pluralForm(l, m.plural, named[CountArg])
+		}
+
+		return interpolate(text, named)
+	}
+
+	return key
+}
+
+func (b *Bundle) pluralForm(locale string, forms map[string]string, count any) string {
+	n, ok := NewOperands(count)
+	if !ok {
+		return forms[Other]
+	}
+
+	if n.N == 0 {
+		if s, ok := forms[Zero]; ok {
+			return s
+		}
+	}
+
+	lang := Base(locale)
+	rule := b.rules[lang]
+	if rule == nil {
+		rule = pluralRules[lang]
+	}
+	if rule == nil {
+		rule = pluralOneOther
+	}
+
+	if s, ok := forms[rule(n)]; ok {
+		return s
+	}
+	return forms[Other]
+}
+
+func namedArgs(args []any) map[string]any {
+	if len(args) == 1 {
+		if m, ok := args[0].(map[string]any); ok {
+			return m
+		}
+	}
+
+	named := make(map[string]any, len(args)/2)
+	for i := 0; i+1 < len(args); i += 2 {
+		named[fmt.Sprint(args[i])] = args[i+1]
+	}
+	return named
+}
+
+// Replace {name} placeholders. Unknown placeholders are kept.
+func interpolate(text string, args map[string]any) string {
+	if len(args) == 0 || !strings.Contains(text, "{") {
+		return text
+	}
+
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(text, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(text[start:], '}')
+		if end < 0 {
+			break
+		}
+
+		name := text[start+1 : start+end]
+		v, ok := args[name]
+
+		sb.WriteString(text[:start])
+		if ok {
+			sb.WriteString(fmt.Sprint(v))
+		} else {
+			sb.WriteString(text[start : start+end+1])
+		}
+		text = text[start+end+1:]
+	}
+	sb.WriteString(text)
+
+	return sb.String()
+}
+
+// Normalize returns tag in lower case with "-" separators, such as "pt-br".
+func Normalize(tag string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+}
+
+// Base returns the language of tag, such as "pt" for "pt-br".
+func Base(tag string) string {
+	lang, _, _ := strings.Cut(Normalize(tag), "-")
+	return lang
+}
+
+// Locales to try for tag, most specific first.
+func fallbacks(tag string) []string {
+	if tag == "" {
+		return nil
+	}
+
+	chain := []string{tag}
+	for {
+		i := strings.LastIndexByte(tag, '-')
+		if i < 0 {
+			return chain
+		}
+		tag = tag[:i]
+		chain = append(chain, tag)
+	}
+}
@@ -0,0 +1,159 @@
+package i18n_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/nexoratech2025/go-telegram-bot-app/i18n"
+)
+
+func newBundle(t *testing.T) *i18n.Bundle {
+	t.Helper()
+
+	b := i18n.NewBundle("en")
+	fsys := fstest.MapFS{
+		"en.json": {Data: []byte(`{
+			"greeting": "Hello, {name}!",
+			"apples": {"zero": "no apples", "one": "{count} apple", "other": "{count} apples"},
+			"menu": {"title": "Menu", "other": {"x": "nested"}}
+		}`)},
+		"ru.json":    {Data: []byte(`{"apples": {"one": "{count} яблоко", "few": "{count} яблока", "many": "{count} яблок", "other": "{count} яблока"}}`)},
+		"pt-BR.json": {Data: []byte(`{"greeting": "Olá, {name}!"}`)},
+	}
+
+	if err := b.LoadFS(fsys); err != nil {
+		t.Fatalf("Expected catalogs to load, found %v", err)
+	}
+	return b
+}
+
+func TestBundleShouldInterpolateArguments(t *testing.T) {
+	// Arrange
+	b := newBundle(t)
+
+	// Act
+	s := b.T("en", "greeting", "name", "Ann")
+
+	// Assert
+	if s != "Hello, Ann!" {
+		t.Errorf("Expected %q, found %q", "Hello, Ann!", s)
+	}
+	if s = b.T("en", "greeting", map[string]any{"name": "Bob"}); s != "Hello, Bob!" {
+		t.Errorf("Expected %q, found %q", "Hello, Bob!", s)
+	}
+	if s = b.T("en", "greeting"); s != "Hello, {name}!" {
+		t.Errorf("Expected placeholder to be kept, found %q", s)
+	}
+}
+
+func TestBundleShouldSelectPluralForms(t *testing.T) {
+	// Arrange
+	b := newBundle(t)
+
+	cases := []struct {
+		locale   string
+		count    any
+		expected string
+	}{
+		{"en", 0, "no apples"},
+		{"en", 1, "1 apple"},
+		{"en", 2, "2 apples"},
+		{"en", 1.5, "1.5 apples"},
+		{"ru", 1, "1 яблоко"},
+		{"ru", 3, "3 яблока"},
+		{"ru", 5, "5 яблок"},
+		{"ru", 11, "11 яблок"},
+		{"ru", 21, "21 яблоко"},
+		{"ru", 22, "22 яблока"},
+		{"ru", 1.5, "1.5 яблока"},
+	}
+
+	for _, c := range cases {
+		// Act
+		s := b.T(c.locale, "apples", "count", c.count)
+
+		// Assert
+		if s != c.expected {
+			t.Errorf("Expected %q for %v in %s, found %q", c.expected, c.count, c.locale, s)
+		}
+	}
+}
+
+func TestBundleShouldFallBack(t *testing.T) {
+	// Arrange
+	b := newBundle(t)
+
+	// Act & Assert
+	if s := b.T("pt-br", "greeting", "name", "Ana"); s != "Olá, Ana!" {
+		t.Errorf("Expected regional message, found %q", s)
+	}
+	if s := b.T("ru", "greeting", "name", "Ivan"); s != "Hello, Ivan!" {
+		t.Errorf("Expected default locale message, found %q", s)
+	}
+	if s := b.T("en", "missing"); s != "missing" {
+		t.Errorf("Expected key for missing message, found %q", s)
+	}
+	if s := b.T("en", "menu.other.x"); s != "nested" {
+		t.Errorf("Expected nested key to be flattened, found %q", s)
+	}
+}
+
+func TestBundleShouldMatchLocales(t *testing.T) {
+	// Arrange
+	b := newBundle(t)
+
+	cases := map[string][]string{
+		"pt-br": {"pt_BR"},
+		"ru":    {"ru-RU"},
+		"en":    {"de", "fr"},
+	}
+
+	for expected, tags := range cases {
+		// Act
+		locale := b.Match(tags...)
+
+		// Assert
+		if locale != expected {
+			t.Errorf("Expected %q for %v, found %q", expected, tags, locale)
+		}
+	}
+}
+
+func TestBundleShouldLoadRegisteredFormats(t *testing.T) {
+	// Arrange
+	b := i18n.NewBundle("en")
+	b.RegisterFormat(".txt", func(data []byte, v any) error {
+		key, value, _ := strings.Cut(strings.TrimSpace(string(data)), "=")
+		return json.Unmarshal([]byte(`{"`+key+`":"`+value+`"}`), v)
+	})
+
+	// Act
+	err := b.LoadFS(fstest.MapFS{"de.txt": {Data: []byte("hi=Hallo")}}, "*.txt")
+
+	// Assert
+	if err != nil || b.T("de", "hi") != "Hallo" {
+		t.Errorf("Expected custom format to load, found %q, %v", b.T("de", "hi"), err)
+	}
+}
+
+func TestBundleShouldUseCustomPluralRule(t *testing.T) {
+	// Arrange
+	b := i18n.NewBundle("xx")
+	b.AddMessages("xx", map[string]any{"n": map[string]any{"few": "few", "other": "other"}})
+	b.SetPluralRule("xx", func(n i18n.Operands) string {
+		if n.I < 5 {
+			return i18n.Few
+		}
+		return i18n.Other
+	})
+
+	// Act & Assert
+	if s := b.T("xx", "n", "count", 3); s != "few" {
+		t.Errorf("Expected %q, found %q", "few", s)
+	}
+	if s := b.T("xx", "n", "count", 7); s != "other" {
+		t.Errorf("Expected %q, found %q", "other", s)
+	}
+}
@@ -0,0 +1,205 @@
+package i18n
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// CLDR plural categories.
+const (
+	Zero  = "zero"
+	One   = "one"
+	Two   = "two"
+	Few   = "few"
+	Many  = "many"
+	Other = "other"
+)
+
+// Operands of a number as defined by CLDR: the absolute value N, its integer
+// digits I and the number of visible fraction digits V.
+type Operands struct {
+	N float64
+	I int64
+	V int
+}
+
+// NewOperands returns the operands of an integer, float or numeric string.
+// ok is false for other values.
+func NewOperands(value any) (op Operands, ok bool) {
+	switch v := value.(type) {
+	case int:
+		return intOperands(int64(v)), true
+	case int8:
+		return intOperands(int64(v)), true
+	case int16:
+		return intOperands(int64(v)), true
+	case int32:
+		return intOperands(int64(v)), true
+	case int64:
+		return intOperands(v), true
+	case uint:
+		return intOperands(int64(v)), true
+	case uint8:
+		return intOperands(int64(v)), true
+	case uint16:
+		return intOperands(int64(v)), true
+	case uint32:
+		return intOperands(int64(v)), true
+	case uint64:
+		return intOperands(int64(v)), true
+	case float32:
+		return NewOperands(strconv.FormatFloat(float64(v), 'f', -1, 32))
+	case float64:
+		return NewOperands(strconv.FormatFloat(v, 'f', -1, 64))
+	case string:
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return Operands{}, false
+		}
+		op = Operands{N: math.Abs(n), I: int64(math.Abs(n))}
+		if _, frac, found := strings.Cut(v, "."); found {
+			op.V = len(frac)
+		}
+		return op, true
+	}
+	return Operands{}, false
+}
+
+func intOperands(n int64) Operands {
+	if n < 0 {
+		n = -n
+	}
+	return Operands{N: float64(n), I: n}
+}
+
+// PluralRule returns the plural category of a number.
+type PluralRule func(n Operands) string
+
+// Cardinal plural rules of common languages, by base language.
+var pluralRules = map[string]PluralRule{}
+
+func init() {
+	for _, lang := range []string{"ja", "zh", "ko", "vi", "th", "id", "ms", "lo", "my", "km"} {
+		pluralRules[lang] = pluralOther
+	}
+	for _, lang := range []string{
+		"en", "de", "nl", "sv", "da", "nb", "no", "nn", "fi", "et", "it", "es", "el",
+		"hu", "tr", "bg", "ca", "eu", "gl", "af", "sq", "az", "ka", "kk", "ky", "uz",
+		"mn", "ur", "sw", "ta", "te", "ml", "kn", "mr", "ne",
+	} {
+		pluralRules[lang] = pluralOneOther
+	}
+	for _, lang := range []string{"fr", "pt", "hi", "bn", "fa", "am", "zu"} {
+		pluralRules[lang] = pluralZeroAndOne
+	}
+	for _, lang := range []string{"ru", "uk", "be"} {
+		pluralRules[lang] = pluralEastSlavic
+	}
+	pluralRules["pl"] = pluralPolish
+	pluralRules["cs"] = pluralCzech
+	pluralRules["sk"] = pluralCzech
+	pluralRules["ar"] = pluralArabic
+	pluralRules["he"] = pluralHebrew
+}
+
+func pluralOther(Operands) string {
+	return Other
+}
+
+func pluralOneOther(n Operands) string {
+	if n.I == 1 && n.V == 0 {
+		return One
+	}
+	return Other
+}
+
+func pluralZeroAndOne(n Operands) string {
+	if n.I == 0 || n.I == 1 {
+		return One
+	}
+	return Other
+}
+
+func pluralEastSlavic(n Operands) string {
+	if n.V != 0 {
+		return Other
+	}
+
+	i10, i100 := n.I%10, n.I%100
+	switch {
+	case i10 == 1 && i100 != 11:
+		return One
+	case i10 >= 2 && i10 <= 4 && (i100 < 12 || i100 > 14):
+		return Few
+	}
+	return Many
+}
+
+func pluralPolish(n Operands) string {
+	if n.V != 0 {
+		return Other
+	}
+
+	i10, i100 := n.I%10, n.I%100
+	switch {
+	case n.I == 1:
+		return One
+	case i10 >= 2 && i10 <= 4 && (i100 < 12 || i100 > 14):
+		return Few
+	}
+	return Many
+}
+
+func pluralCzech(n Operands) string {
+	switch {
+	case n.V != 0:
+		return Many
+	case n.I == 1:
+		return One
+	case n.I >= 2 && n.I <= 4:
+		return Few
+	}
+	return Other
+}
+
+func pluralArabic(n Operands) string {
+	if n.V != 0 {
+		return Other
+	}
+
+	i100 := n.I % 100
+	switch {
+	case n.I == 0:
+		return Zero
+	case n.I == 1:
+		return One
+	case n.I == 2:
+		return Two
+	case i100 >= 3 && i100 <= 10:
+		return Few
+	case i100 >= 11:
+		return Many
+	}
+	return Other
+}
+
+func pluralHebrew(n Operands) string {
+	switch {
+	case n.V != 0:
+		return Other
+	case n.I == 1:
+		return One
+	case n.I == 2:
+		return Two
+	}
+	return Other
+}
+
+func isPluralCategory(s string) bool {
+	switch s {
+	case Zero, One, Two, Few, Many, Other:
+		return true
+	}
+	return false
+}
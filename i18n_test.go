@@ -0,0 +1,86 @@
+package tgbotapp_test
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+
+	tgbotapp "github.com/nexoratech2025/go-telegram-bot-app"
+	"github.com/nexoratech2025/go-telegram-bot-app/i18n"
+)
+
+func newI18nHandlerContext(t *testing.T, languageCode string) *tgbotapp.HandlerContext {
+	t.Helper()
+
+	bundle := i18n.NewBundle("en")
+	bundle.AddMessages("en", map[string]any{"hello": "Hello, {name}"})
+	bundle.AddMessages("de", map[string]any{"hello": "Hallo, {name}"})
+	bundle.AddMessages("fr", map[string]any{"hello": "Bonjour, {name}"})
+
+	app := tgbotapp.New(nil, func(a *tgbotapp.Application) {
+		a.Logger = slog.Default()
+	}, tgbotapp.WithI18n(bundle))
+
+	update := newChatUpdate(123, "hi")
+	update.Message.From.LanguageCode = languageCode
+
+	ctx := tgbotapp.NewBotContext(t.Context(), app, update)
+	ctx.Session = tgbotapp.NewDefaultSession()
+	return tgbotapp.NewHandlerContext(ctx, "test")
+}
+
+func TestHandlerContextShouldTranslateIntoUserLanguage(t *testing.T) {
+	// Arrange
+	h := newI18nHandlerContext(t, "de-AT")
+
+	// Act
+	s := h.T("hello", "name", "Eva")
+
+	// Assert
+	if h.Locale() != "de" || s != "Hallo, Eva" {
+		t.Errorf("Expected German translation, found %q in %q", s, h.Locale())
+	}
+}
+
+func TestHandlerContextShouldPreferSessionLocale(t *testing.T) {
+	// Arrange
+	h := newI18nHandlerContext(t, "de")
+
+	// Act
+	err := h.SetLocale("fr")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected locale to be set, found %v", err)
+	}
+	if s := h.T("hello", "name", "Eva"); s != "Bonjour, Eva" {
+		t.Errorf("Expected session locale to win, found %q", s)
+	}
+}
+
+func TestHandlerContextSetLocaleShouldFailWithoutSession(t *testing.T) {
+	// Arrange
+	h := newI18nHandlerContext(t, "de")
+	h.Session = nil
+
+	// Act
+	err := h.SetLocale("fr")
+
+	// Assert
+	if !errors.Is(err, tgbotapp.ErrNoSession) {
+		t.Errorf("Expected ErrNoSession, found %v", err)
+	}
+}
+
+func TestHandlerContextShouldFallBackToDefaultLocale(t *testing.T) {
+	// Arrange
+	h := newI18nHandlerContext(t, "ja")
+
+	// Act
+	s := h.T("hello", "name", "Eva")
+
+	// Assert
+	if s != "Hello, Eva" {
+		t.Errorf("Expected default locale, found %q", s)
+	}
+}
@@ -0,0 +1,418 @@
+package tgbotapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNoJobScheduler = errors.New("Application has no job scheduler.")
+	ErrJobNotFound    = errors.New("Job not found.")
+	ErrNoNextRun      = errors.New("Job has no next run time.")
+)
+
+// Clock tells the time to the job scheduler. Tests can provide a clock they
+// advance themselves.
+type Clock interface {
+	Now() time.Time
+	// Return a channel receiving the time once t is reached.
+	Until(t time.Time) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                     { return time.Now() }
+func (systemClock) Until(t time.Time) <-chan time.Time { return time.After(time.Until(t)) }
+
+// Clock of the real time.
+var SystemClock Clock = systemClock{}
+
+// Job is a scheduled run of a function registered with RegisterJob.
+type Job struct {
+	// Unique id of the job. Scheduling a job with the id of another job
+	// replaces it.
+	ID string
+	// Name of the registered function.
+	Name string
+	// Cron expression of a recurring job, see ParseCron. Empty for a job
+	// that runs once.
+	Cron string
+	// Next time the job runs.
+	Next time.Time
+	// Optional chat the job is about.
+	ChatID int64
+	// Optional data for the function.
+	Payload string
+}
+
+// JobStore persists scheduled jobs so that they survive restarts.
+type JobStore interface {
+	Save(ctx context.Context, job Job) error
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context) ([]Job, error)
+}
+
+type MemoryJobStore struct {
+	mu   sync.Mutex
+	jobs map[string]Job
+}
+
+func NewMemoryJobStore() *MemoryJobStore {
+	return &MemoryJobStore{jobs: make(map[string]Job)}
+}
+
+func (s *MemoryJobStore) Save(ctx context.Context, job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.ID] = job
+	return nil
+}
+
+func (s *MemoryJobStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, id)
+	return nil
+}
+
+func (s *MemoryJobStore) List(ctx context.Context) ([]Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Collect(maps.Values(s.jobs)), nil
+}
+
+type JobFunc func(*JobContext) error
+
+// JobContext is passed to the function of a job when it runs.
+type JobContext struct {
+	app *Application
+
+	Ctx    context.Context
+	BotAPI *tgbotapi.BotAPI
+	// Sender of the application, for requests that are not sent with Chat.
+	Sender Sender
+	Logger *slog.Logger
+	Job    Job
+}
+
+// Return a handler context for chatID, to send messages with the
+// HandlerContext helpers. Its update only carries the chat; it has no
+// session and no user.
+func (j *JobContext) Chat(chatID int64) *HandlerContext {
+	ctx := NewBotContext(WithPriority(j.Ctx, PriorityNormal), j.app, chatUpdate(chatID))
+	return NewHandlerContext(ctx, "job:"+j.Job.Name)
+}
+
+func chatUpdate(chatID int64) *tgbotapi.Update {
+	return &tgbotapi.Update{
+		Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: chatID}},
+	}
+}
+
+// Control the job scheduler options.
+type JobOption func(*JobScheduler)
+
+// Persist jobs in store. Defaults to a memory store.
+func WithJobStore(store JobStore) JobOption {
+	return func(s *JobScheduler) {
+		s.store = store
+	}
+}
+
+// Tell the time with clock. Defaults to SystemClock.
+func WithClock(clock Clock) JobOption {
+	return func(s *JobScheduler) {
+		s.clock = clock
+	}
+}
+
+// JobScheduler runs registered functions at the times of their jobs. Each
+// run happens in its own goroutine. A recurring job is rescheduled when it
+// starts; a job that runs once is removed from the store before it runs,
+// so it runs at most once. Jobs that were due while the application was
+// stopped run when it starts.
+type JobScheduler struct {
+	app   *Application
+	store JobStore
+	clock Clock
+
+	mu    sync.Mutex
+	funcs map[string]JobFunc
+	jobs  map[string]Job
+
+	wake chan struct{}
+	wg   sync.WaitGroup
+}
+
+// Return new scheduler of app's jobs. It runs jobs once started.
+func NewJobScheduler(app *Application, opts ...JobOption) *JobScheduler {
+	s := &JobScheduler{
+		app:   app,
+		store: NewMemoryJobStore(),
+		clock: SystemClock,
+		funcs: make(map[string]JobFunc),
+		jobs:  make(map[string]Job),
+		wake:  make(chan struct{}, 1),
+	}
+	s.funcs[timeoutJobName] = s.runTimeout
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+func (s *JobScheduler) Clock() Clock {
+	return s.clock
+}
+
+// Register fn as the function of the jobs named name.
+func (s *JobScheduler) Register(name string, fn JobFunc) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.funcs[name]; ok {
+		return fmt.Errorf("Job function %q already exists.", name)
+	}
+	s.funcs[name] = fn
+
+	return nil
+}
+
+// Schedule job. An empty ID is replaced by a new one, and the next time of
+// a recurring job is computed from its cron expression. Returns ErrNoNextRun
+// if the job has no next time, such as for a cron expression that never
+// matches.
+func (s *JobScheduler) Schedule(ctx context.Context, job Job) (Job, error) {
+	if job.ID == "" {
+		job.ID = uuid.NewString()
+	}
+
+	if job.Cron != "" {
+		schedule, err := ParseCron(job.Cron)
+		if err != nil {
+			return job, err
+		}
+		job.Next = schedule.Next(s.clock.Now())
+	}
+	if job.Next.IsZero() {
+		return job, ErrNoNextRun
+	}
+
+	if err := s.store.Save(ctx, job); err != nil {
+		return job, err
+	}
+
+	s.mu.Lock()
+	s.jobs[job.ID] = job
+	s.mu.Unlock()
+	s.signal()
+
+	return job, nil
+}
+
+// Schedule job to run once after d.
+func (s *JobScheduler) After(ctx context.Context, d time.Duration, job Job) (Job, error) {
+	job.Cron = ""
+	job.Next = s.clock.Now().Add(d)
+	return s.Schedule(ctx, job)
+}
+
+// Schedule job to run at the times of the cron expression spec.
+func (s *JobScheduler) Cron(ctx context.Context, spec string, job Job) (Job, error) {
+	job.Cron = spec
+	return s.Schedule(ctx, job)
+}
+
+// Remove the job id. Returns ErrJobNotFound if there is no such job.
+func (s *JobScheduler) Cancel(ctx context.Context, id string) error {
+	s.mu.Lock()
+	_, ok := s.jobs[id]
+	delete(s.jobs, id)
+	s.mu.Unlock()
+
+	if !ok {
+		return ErrJobNotFound
+	}
+
+	s.signal()
+	return s.store.Delete(ctx, id)
+}
+
+// Return the job id, if it is scheduled.
+func (s *JobScheduler) Get(id string) (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	return job, ok
+}
+
+// Return the scheduled jobs ordered by next run.
+func (s *JobScheduler) Jobs() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := slices.Collect(maps.Values(s.jobs))
+	slices.SortFunc(jobs, func(a, b Job) int {
+		return a.Next.Compare(b.Next)
+	})
+	return jobs
+}
+
+// Load the stored jobs and run them until ctx is done, then wait for the
+// running jobs.
+func (s *JobScheduler) Run(ctx context.Context) error {
+	jobs, err := s.store.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	for _, job := range jobs {
+		s.jobs[job.ID] = job
+	}
+	s.mu.Unlock()
+
+	defer s.wg.Wait()
+
+	for {
+		s.runDue(ctx)
+
+		var timer <-chan time.Time
+		if next, ok := s.next(); ok {
+			timer = s.clock.Until(next)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer:
+		case <-s.wake:
+		}
+	}
+}
+
+func (s *JobScheduler) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *JobScheduler) next() (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var next time.Time
+	for _, job := range s.jobs {
+		if job.Next.IsZero() {
+			continue
+		}
+		if next.IsZero() || job.Next.Before(next) {
+			next = job.Next
+		}
+	}
+	return next, !next.IsZero()
+}
+
+func (s *JobScheduler) runDue(ctx context.Context) {
+	now := s.clock.Now()
+
+	s.mu.Lock()
+	var due []Job
+	for _, job := range s.jobs {
+		if !job.Next.IsZero() && !job.Next.After(now) {
+			due = append(due, job)
+		}
+	}
+	s.mu.Unlock()
+
+	slices.SortFunc(due, func(a, b Job) int {
+		return a.Next.Compare(b.Next)
+	})
+
+	for _, job := range due {
+		ok, err := s.reschedule(ctx, job, now)
+		if err != nil {
+			s.app.Logger.ErrorContext(ctx, "Cannot reschedule job.", "job", job.Name, "id", job.ID, "error_detail", err)
+		}
+		if !ok {
+			continue
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.run(ctx, job)
+		}()
+	}
+}
+
+// Store the next run of job, or remove it if it does not run again.
+// Returns false if job must not run because it was canceled or replaced
+// meanwhile. A job whose store update failed still runs, and runs again if
+// the scheduler restarts with the stale record.
+func (s *JobScheduler) reschedule(ctx context.Context, job Job, now time.Time) (bool, error) {
+	var next time.Time
+	if job.Cron != "" {
+		schedule, err := ParseCron(job.Cron)
+		if err != nil {
+			return false, err
+		}
+		next = schedule.Next(now)
+	}
+
+	s.mu.Lock()
+	if current, ok := s.jobs[job.ID]; !ok || !current.Next.Equal(job.Next) {
+		s.mu.Unlock()
+		return false, nil
+	}
+	if next.IsZero() {
+		delete(s.jobs, job.ID)
+	} else {
+		job.Next = next
+		s.jobs[job.ID] = job
+	}
+	s.mu.Unlock()
+
+	if next.IsZero() {
+		return true, s.store.Delete(ctx, job.ID)
+	}
+	return true, s.store.Save(ctx, job)
+}
+
+func (s *JobScheduler) run(ctx context.Context, job Job) {
+	s.mu.Lock()
+	fn, ok := s.funcs[job.Name]
+	s.mu.Unlock()
+
+	logger := s.app.Logger.With(slog.String("type", "job"), slog.String("name", job.Name), slog.String("id", job.ID))
+
+	if !ok {
+		logger.ErrorContext(ctx, "No function found for job.")
+		return
+	}
+
+	jc := &JobContext{
+		app:    s.app,
+		Ctx:    ctx,
+		BotAPI: s.app.BotAPI,
+		Sender: s.app.newSender(s.app.Outbound),
+		Logger: logger,
+		Job:    job,
+	}
+
+	if err := fn(jc); err != nil {
+		logger.ErrorContext(ctx, "Job failed.", "error_detail", err)
+	}
+}
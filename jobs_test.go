@@ -0,0 +1,254 @@
+package tgbotapp_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	tgbotapp "github.com/nexoratech2025/go-telegram-bot-app"
+	"github.com/nexoratech2025/go-telegram-bot-app/testutil"
+)
+
+var jobsEpoch = time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+// Return a scheduler using a fake clock, and the fake Bot API of its bot.
+func newJobScheduler(t *testing.T, opts ...tgbotapp.JobOption) (*tgbotapp.JobScheduler, *testutil.FakeClock, *testutil.FakeBotAPI) {
+	t.Helper()
+
+	f := testutil.NewFakeBotAPI(t)
+	clock := testutil.NewFakeClock(jobsEpoch)
+	app := tgbotapp.New(f.NewBotAPI(t), func(a *tgbotapp.Application) {
+		a.Logger = slog.Default()
+	}, tgbotapp.WithJobs(append([]tgbotapp.JobOption{tgbotapp.WithClock(clock)}, opts...)...))
+
+	return app.Jobs, clock, f
+}
+
+func runJobs(t *testing.T, s *tgbotapp.JobScheduler) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func waitJob(t *testing.T, ran <-chan tgbotapp.Job) tgbotapp.Job {
+	t.Helper()
+	select {
+	case job := <-ran:
+		return job
+	case <-time.After(time.Second):
+		t.Fatal("Expected job to run")
+		return tgbotapp.Job{}
+	}
+}
+
+func recordJob(ran chan<- tgbotapp.Job) tgbotapp.JobFunc {
+	return func(jc *tgbotapp.JobContext) error {
+		ran <- jc.Job
+		return nil
+	}
+}
+
+func TestJobSchedulerShouldRunDelayedJobOnce(t *testing.T) {
+	// Arrange
+	s, clock, _ := newJobScheduler(t)
+	ran := make(chan tgbotapp.Job, 1)
+	s.Register("remind", recordJob(ran))
+
+	job, _ := s.After(t.Context(), time.Minute, tgbotapp.Job{Name: "remind", Payload: "hi"})
+	runJobs(t, s)
+
+	// Act
+	clock.Advance(time.Minute)
+
+	// Assert
+	if got := waitJob(t, ran); got.ID != job.ID || got.Payload != "hi" {
+		t.Errorf("Expected job %s to run, found %+v", job.ID, got)
+	}
+	if _, ok := s.Get(job.ID); ok {
+		t.Error("Expected job to be removed after running")
+	}
+}
+
+func TestJobSchedulerShouldRescheduleCronJobs(t *testing.T) {
+	// Arrange
+	s, clock, _ := newJobScheduler(t)
+	ran := make(chan tgbotapp.Job, 2)
+	s.Register("tick", recordJob(ran))
+
+	job, err := s.Cron(t.Context(), "*/10 * * * *", tgbotapp.Job{ID: "tick", Name: "tick"})
+	if err != nil {
+		t.Fatalf("Expected job to be scheduled, found %v", err)
+	}
+	runJobs(t, s)
+
+	// Act
+	clock.Advance(10 * time.Minute)
+	waitJob(t, ran)
+
+	// Assert
+	if !job.Next.Equal(jobsEpoch.Add(10 * time.Minute)) {
+		t.Errorf("Expected first run at 10:40, found %v", job.Next)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		next, _ := s.Get("tick")
+		if next.Next.Equal(jobsEpoch.Add(20 * time.Minute)) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected next run at 10:50, found %v", next.Next)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestJobSchedulerShouldNotRunCanceledJobs(t *testing.T) {
+	// Arrange
+	s, clock, _ := newJobScheduler(t)
+	ran := make(chan tgbotapp.Job, 1)
+	s.Register("remind", recordJob(ran))
+
+	job, _ := s.After(t.Context(), time.Minute, tgbotapp.Job{Name: "remind"})
+	runJobs(t, s)
+
+	// Act
+	err := s.Cancel(t.Context(), job.ID)
+	clock.Advance(time.Hour)
+
+	// Assert
+	if err != nil {
+		t.Errorf("Expected job to be canceled, found %v", err)
+	}
+	select {
+	case <-ran:
+		t.Error("Expected canceled job not to run")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if err := s.Cancel(t.Context(), job.ID); !errors.Is(err, tgbotapp.ErrJobNotFound) {
+		t.Errorf("Expected ErrJobNotFound, found %v", err)
+	}
+}
+
+func TestJobSchedulerShouldRejectJobsWithoutNextRun(t *testing.T) {
+	// Arrange
+	s, _, _ := newJobScheduler(t)
+
+	// Act
+	_, cronErr := s.Cron(t.Context(), "0 0 30 2 *", tgbotapp.Job{Name: "remind"})
+	_, emptyErr := s.Schedule(t.Context(), tgbotapp.Job{Name: "remind"})
+
+	// Assert
+	if !errors.Is(cronErr, tgbotapp.ErrNoNextRun) || !errors.Is(emptyErr, tgbotapp.ErrNoNextRun) {
+		t.Errorf("Expected ErrNoNextRun, found %v, %v", cronErr, emptyErr)
+	}
+	if jobs := s.Jobs(); len(jobs) != 0 {
+		t.Errorf("Expected no scheduled job, found %+v", jobs)
+	}
+}
+
+func TestJobSchedulerShouldIgnoreStoredJobsWithoutNextRun(t *testing.T) {
+	// Arrange
+	store := tgbotapp.NewMemoryJobStore()
+	store.Save(t.Context(), tgbotapp.Job{ID: "broken", Name: "remind"})
+	store.Save(t.Context(), tgbotapp.Job{ID: "due", Name: "remind", Next: jobsEpoch.Add(time.Minute)})
+
+	s, clock, _ := newJobScheduler(t, tgbotapp.WithJobStore(store))
+	ran := make(chan tgbotapp.Job, 2)
+	s.Register("remind", recordJob(ran))
+	runJobs(t, s)
+
+	// Act
+	clock.Advance(time.Minute)
+
+	// Assert
+	if got := waitJob(t, ran); got.ID != "due" {
+		t.Errorf("Expected job due after a minute to run, found %+v", got)
+	}
+}
+
+// Job store whose deletes fail.
+type undeletableJobStore struct {
+	*tgbotapp.MemoryJobStore
+}
+
+func (undeletableJobStore) Delete(ctx context.Context, id string) error {
+	return errors.New("store unavailable")
+}
+
+func TestJobSchedulerShouldRunJobsWhenStoreDeleteFails(t *testing.T) {
+	// Arrange
+	s, clock, _ := newJobScheduler(t, tgbotapp.WithJobStore(undeletableJobStore{tgbotapp.NewMemoryJobStore()}))
+	ran := make(chan tgbotapp.Job, 1)
+	s.Register("remind", recordJob(ran))
+
+	s.After(t.Context(), time.Minute, tgbotapp.Job{ID: "once", Name: "remind"})
+	runJobs(t, s)
+
+	// Act
+	clock.Advance(time.Minute)
+
+	// Assert
+	if got := waitJob(t, ran); got.ID != "once" {
+		t.Errorf("Expected job to run, found %+v", got)
+	}
+}
+
+func TestJobSchedulerShouldRunStoredJobsOnStart(t *testing.T) {
+	// Arrange
+	store := tgbotapp.NewMemoryJobStore()
+	store.Save(t.Context(), tgbotapp.Job{ID: "missed", Name: "remind", Next: jobsEpoch.Add(-time.Hour)})
+
+	s, _, _ := newJobScheduler(t, tgbotapp.WithJobStore(store))
+	ran := make(chan tgbotapp.Job, 1)
+	s.Register("remind", recordJob(ran))
+
+	// Act
+	runJobs(t, s)
+
+	// Assert
+	if got := waitJob(t, ran); got.ID != "missed" {
+		t.Errorf("Expected stored job to run, found %+v", got)
+	}
+}
+
+func TestJobContextChatShouldSendToChat(t *testing.T) {
+	// Arrange
+	s, clock, f := newJobScheduler(t)
+	errs := make(chan error, 1)
+	s.Register("greet", func(jc *tgbotapp.JobContext) error {
+		_, err := jc.Chat(jc.Job.ChatID).SendMessage("hello")
+		errs <- err
+		return err
+	})
+
+	s.After(t.Context(), time.Second, tgbotapp.Job{Name: "greet", ChatID: 7})
+	runJobs(t, s)
+
+	// Act
+	clock.Advance(time.Second)
+
+	// Assert
+	select {
+	case err := <-errs:
+		if err != nil {
+			t.Fatalf("Expected message to be sent, found %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected job to run")
+	}
+	if _, params := lastCall(f, "sendMessage"); params.Get("chat_id") != "7" {
+		t.Errorf("Expected message to chat 7, found %v", params)
+	}
+}
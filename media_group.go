@@ -0,0 +1,269 @@
+package tgbotapp
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// Context data key of the messages of an album, see HandlerContext.MediaGroup.
+	MediaGroupDataKey = "media_group"
+	// Name of the document handler that receives whole albums.
+	MediaGroupHandlerName = "media_group"
+
+	// Maximum number of items of an album.
+	MaxMediaGroupSize = 10
+)
+
+var (
+	ErrInvalidMediaGroup = errors.New("Media group must have 2 to 10 items.")
+)
+
+type pendingMediaGroup struct {
+	ctx      *BotContext
+	next     HandlerFunc
+	messages []*tgbotapi.Message
+	timer    *time.Timer
+	// Sequence number of the timer that may flush the album.
+	seq uint64
+}
+
+type mediaGroupKey struct {
+	chatID int64
+	id     string
+}
+
+// Lock serializing the handling of the updates of a chat.
+type chatLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// MediaGroupAggregator collects the updates of an album, which Telegram sends
+// one per item, and handles them as a single update once no new item arrived
+// for the debounce window. The first update of the album is passed on with
+// all messages stored under MediaGroupDataKey.
+//
+// Delayed albums are handled from a timer, so the aggregator also serializes
+// the handling of the other updates of the same chat. Its middleware must run
+// before the session and routing middlewares.
+type MediaGroupAggregator struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	pending map[mediaGroupKey]*pendingMediaGroup
+	locks   map[int64]*chatLock
+	seq     uint64
+
+	// Counts albums that are not handled yet.
+	wg sync.WaitGroup
+}
+
+func NewMediaGroupAggregator(window time.Duration) *MediaGroupAggregator {
+	return &MediaGroupAggregator{
+		window:  window,
+		pending: make(map[mediaGroupKey]*pendingMediaGroup),
+		locks:   make(map[int64]*chatLock),
+	}
+}
+
+func (g *MediaGroupAggregator) Middleware() Middleware {
+	return func(ctx *BotContext, next HandlerFunc) {
+		msg := mediaGroupMessage(ctx.Update)
+		if msg == nil {
+			var chatID int64
+			if chat := ctx.Update.FromChat(); chat != nil {
+				chatID = chat.ID
+			}
+			defer g.lock(chatID)()
+			next(ctx)
+			return
+		}
+
+		g.add(ctx, next, msg)
+	}
+}
+
+// Lock the updates of chatID and return the function unlocking them.
+func (g *MediaGroupAggregator) lock(chatID int64) func() {
+	g.mu.Lock()
+	l, ok := g.locks[chatID]
+	if !ok {
+		l = &chatLock{}
+		g.locks[chatID] = l
+	}
+	l.refs++
+	g.mu.Unlock()
+
+	l.mu.Lock()
+
+	return func() {
+		l.mu.Unlock()
+
+		g.mu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(g.locks, chatID)
+		}
+		g.mu.Unlock()
+	}
+}
+
+func mediaGroupMessage(update *tgbotapi.Update) *tgbotapi.Message {
+	if update == nil {
+		return nil
+	}
+
+	msg := update.Message
+	if msg == nil {
+		msg = update.ChannelPost
+	}
+	if msg == nil || msg.MediaGroupID == "" || msg.Chat == nil {
+		return nil
+	}
+
+	return msg
+}
+
+func (g *MediaGroupAggregator) add(ctx *BotContext, next HandlerFunc, msg *tgbotapi.Message) {
+	key := mediaGroupKey{chatID: msg.Chat.ID, id: msg.MediaGroupID}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	p, ok := g.pending[key]
+	if !ok {
+		p = &pendingMediaGroup{ctx: ctx, next: next}
+		g.pending[key] = p
+		g.wg.Add(1)
+	} else {
+		p.timer.Stop()
+	}
+
+	p.messages = append(p.messages, msg)
+
+	if len(p.messages) >= MaxMediaGroupSize {
+		go g.flush(key, 0)
+		return
+	}
+
+	// A stopped timer may already be running its function, so every timer
+	// only flushes the album if no item arrived since it was started.
+	g.seq++
+	seq := g.seq
+	p.seq = seq
+	p.timer = time.AfterFunc(g.window, func() { g.flush(key, seq) })
+}
+
+// Handle the album key. A non-zero seq must match the sequence number of the
+// album's timer.
+func (g *MediaGroupAggregator) flush(key mediaGroupKey, seq uint64) {
+	g.mu.Lock()
+	p, ok := g.pending[key]
+	if ok && seq != 0 && p.seq != seq {
+		ok = false
+	}
+	if ok {
+		delete(g.pending, key)
+	}
+	g.mu.Unlock()
+
+	if !ok {
+		return
+	}
+	defer g.wg.Done()
+
+	slices.SortFunc(p.messages, func(a, b *tgbotapi.Message) int {
+		return a.MessageID - b.MessageID
+	})
+	p.ctx.SetData(MediaGroupDataKey, p.messages)
+
+	// Albums still waiting when the application stops are handled with
+	// the values of their context, but not its cancellation.
+	if p.ctx.Ctx != nil && p.ctx.Ctx.Err() != nil {
+		p.ctx.Ctx = context.WithoutCancel(p.ctx.Ctx)
+	}
+
+	defer g.lock(key.chatID)()
+	p.next(p.ctx)
+}
+
+// Flush handles all albums that are still waiting for items and waits until
+// they are handled.
+func (g *MediaGroupAggregator) Flush() {
+	g.mu.Lock()
+	keys := make([]mediaGroupKey, 0, len(g.pending))
+	for key, p := range g.pending {
+		p.timer.Stop()
+		keys = append(keys, key)
+	}
+	g.mu.Unlock()
+
+	for _, key := range keys {
+		g.flush(key, 0)
+	}
+
+	g.wg.Wait()
+}
+
+// Messages of the album being handled, ordered by message id, or nil if the
+// update is not an album.
+func (h *HandlerContext) MediaGroup() []*tgbotapi.Message {
+	v, _ := h.GetData(MediaGroupDataKey)
+	messages, _ := v.([]*tgbotapi.Message)
+	return messages
+}
+
+func MediaPhoto(file tgbotapi.RequestFileData, caption string) tgbotapi.InputMediaPhoto {
+	m := tgbotapi.NewInputMediaPhoto(file)
+	m.Caption = caption
+	return m
+}
+
+func MediaVideo(file tgbotapi.RequestFileData, caption string) tgbotapi.InputMediaVideo {
+	m := tgbotapi.NewInputMediaVideo(file)
+	m.Caption = caption
+	return m
+}
+
+func MediaDocument(file tgbotapi.RequestFileData, caption string) tgbotapi.InputMediaDocument {
+	m := tgbotapi.NewInputMediaDocument(file)
+	m.Caption = caption
+	return m
+}
+
+func MediaAudio(file tgbotapi.RequestFileData, caption string) tgbotapi.InputMediaAudio {
+	m := tgbotapi.NewInputMediaAudio(file)
+	m.Caption = caption
+	return m
+}
+
+// Send an album of 2 to 10 items built with MediaPhoto, MediaVideo,
+// MediaDocument, MediaAudio or the tgbotapi.NewInputMedia functions.
+// Documents and audios cannot be mixed with other types.
+func (h *HandlerContext) SendMediaGroup(media ...interface{}) ([]tgbotapi.Message, error) {
+	return h.SendMediaGroupWithOptions(media)
+}
+
+// Send an album like SendMediaGroup. See SendOption for the available options.
+func (h *HandlerContext) SendMediaGroupWithOptions(media []interface{}, opts ...SendOption) ([]tgbotapi.Message, error) {
+	if len(media) < 2 || len(media) > MaxMediaGroupSize {
+		return nil, ErrInvalidMediaGroup
+	}
+
+	o := newSendOptions(opts)
+	cfg := o.apply(tgbotapi.NewMediaGroup(h.GetChatID(), media)).(tgbotapi.MediaGroupConfig)
+
+	var messages []tgbotapi.Message
+	err := h.requestResult(withExtraParams(h.Ctx, o.extra(cfg)), h.GetChatID(), cfg, &messages)
+	if err != nil {
+		h.LogError("Cannot send album.", err)
+		return nil, err
+	}
+
+	return messages, nil
+}
@@ -0,0 +1,243 @@
+package tgbotapp_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	tgbotapp "github.com/nexoratech2025/go-telegram-bot-app"
+	"github.com/nexoratech2025/go-telegram-bot-app/testutil"
+)
+
+func newAlbumUpdate(chatID int64, messageID int, groupID string) *tgbotapi.Update {
+	update := newChatUpdate(chatID, "")
+	update.Message.MessageID = messageID
+	update.Message.MediaGroupID = groupID
+	update.Message.Photo = []tgbotapi.PhotoSize{{FileID: "photo"}}
+	return update
+}
+
+type albumRecorder struct {
+	mu    sync.Mutex
+	calls [][]*tgbotapi.Message
+	done  chan struct{}
+}
+
+func newAlbumRecorder() *albumRecorder {
+	return &albumRecorder{done: make(chan struct{}, 10)}
+}
+
+func (r *albumRecorder) handle(ctx *tgbotapp.BotContext) {
+	r.mu.Lock()
+	v, _ := ctx.GetData(tgbotapp.MediaGroupDataKey)
+	album, _ := v.([]*tgbotapi.Message)
+	r.calls = append(r.calls, album)
+	r.mu.Unlock()
+	r.done <- struct{}{}
+}
+
+func (r *albumRecorder) wait(t *testing.T) {
+	t.Helper()
+	select {
+	case <-r.done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected handler to be called")
+	}
+}
+
+func TestMediaGroupAggregatorShouldDispatchAlbumOnce(t *testing.T) {
+	// Arrange
+	g := tgbotapp.NewMediaGroupAggregator(30 * time.Millisecond)
+	mw := g.Middleware()
+	rec := newAlbumRecorder()
+
+	// Act
+	for _, id := range []int{3, 1, 2} {
+		mw(tgbotapp.NewBotContext(t.Context(), nil, newAlbumUpdate(1, id, "album")), rec.handle)
+	}
+	mw(tgbotapp.NewBotContext(t.Context(), nil, newChatUpdate(1, "text")), rec.handle)
+	rec.wait(t)
+	rec.wait(t)
+
+	// Assert
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if len(rec.calls) != 2 {
+		t.Fatalf("Expected 2 handler calls, found %d", len(rec.calls))
+	}
+	if rec.calls[0] != nil {
+		t.Errorf("Expected plain update without album, found %d items", len(rec.calls[0]))
+	}
+
+	album := rec.calls[1]
+	if len(album) != 3 || album[0].MessageID != 1 || album[2].MessageID != 3 {
+		t.Errorf("Expected 3 album items ordered by id, found %+v", album)
+	}
+}
+
+func TestMediaGroupAggregatorShouldDispatchFullAlbumImmediately(t *testing.T) {
+	// Arrange
+	g := tgbotapp.NewMediaGroupAggregator(time.Hour)
+	mw := g.Middleware()
+	rec := newAlbumRecorder()
+
+	// Act
+	for id := range tgbotapp.MaxMediaGroupSize {
+		mw(tgbotapp.NewBotContext(t.Context(), nil, newAlbumUpdate(1, id, "album")), rec.handle)
+	}
+	rec.wait(t)
+
+	// Assert
+	if n := len(rec.calls[0]); n != tgbotapp.MaxMediaGroupSize {
+		t.Errorf("Expected %d items, found %d", tgbotapp.MaxMediaGroupSize, n)
+	}
+}
+
+func TestMediaGroupAggregatorFlushShouldDispatchPendingAlbums(t *testing.T) {
+	// Arrange
+	g := tgbotapp.NewMediaGroupAggregator(time.Hour)
+	mw := g.Middleware()
+	rec := newAlbumRecorder()
+
+	mw(tgbotapp.NewBotContext(t.Context(), nil, newAlbumUpdate(1, 1, "a")), rec.handle)
+	mw(tgbotapp.NewBotContext(t.Context(), nil, newAlbumUpdate(2, 2, "a")), rec.handle)
+
+	// Act
+	g.Flush()
+
+	// Assert
+	if len(rec.calls) != 2 {
+		t.Errorf("Expected albums of both chats to be dispatched, found %d", len(rec.calls))
+	}
+}
+
+func TestMediaGroupAggregatorShouldOnlySerializeUpdatesOfTheSameChat(t *testing.T) {
+	// Arrange
+	g := tgbotapp.NewMediaGroupAggregator(time.Millisecond)
+	mw := g.Middleware()
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	mw(tgbotapp.NewBotContext(t.Context(), nil, newAlbumUpdate(1, 1, "a")), func(*tgbotapp.BotContext) {
+		close(entered)
+		<-release
+	})
+	<-entered
+	defer close(release)
+
+	// Act
+	done := make(chan struct{})
+	go func() {
+		mw(tgbotapp.NewBotContext(t.Context(), nil, newChatUpdate(2, "text")), func(*tgbotapp.BotContext) {})
+		close(done)
+	}()
+
+	// Assert
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected update of another chat not to wait for the album")
+	}
+}
+
+func TestMediaGroupAggregatorFlushShouldNotPassCanceledContext(t *testing.T) {
+	// Arrange
+	g := tgbotapp.NewMediaGroupAggregator(time.Hour)
+	mw := g.Middleware()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	var err error
+	mw(tgbotapp.NewBotContext(ctx, nil, newAlbumUpdate(1, 1, "a")), func(c *tgbotapp.BotContext) {
+		err = c.Ctx.Err()
+	})
+	cancel()
+
+	// Act
+	g.Flush()
+
+	// Assert
+	if err != nil {
+		t.Errorf("Expected album to be handled with a live context, found %v", err)
+	}
+}
+
+func TestSendMediaGroupShouldRejectInvalidSizes(t *testing.T) {
+	// Arrange
+	app := tgbotapp.New(nil, func(a *tgbotapp.Application) { a.Logger = slog.Default() })
+	h := tgbotapp.NewHandlerContext(tgbotapp.NewBotContext(t.Context(), app, newChatUpdate(1, "")), "test")
+
+	// Act
+	_, err := h.SendMediaGroup(tgbotapp.MediaPhoto(tgbotapi.FileID("a"), "only one"))
+
+	// Assert
+	if !errors.Is(err, tgbotapp.ErrInvalidMediaGroup) {
+		t.Errorf("Expected ErrInvalidMediaGroup, found %v", err)
+	}
+}
+
+func TestMediaGroupsShouldKeepTheHandlersOfOtherChats(t *testing.T) {
+	// Arrange
+	f := testutil.NewFakeBotAPI(t)
+	app := tgbotapp.Default(f.NewBotAPI(t), tgbotapp.WithMediaGroups(time.Millisecond))
+
+	const rounds, texts = 5, 5
+	var mu sync.Mutex
+	var wrong []string
+	handled := make(chan struct{}, rounds*(texts+1))
+	record := func(kind string, chatID int64) tgbotapp.HandlerFunc {
+		return func(ctx *tgbotapp.BotContext) {
+			if id := ctx.Update.FromChat().ID; id != chatID {
+				mu.Lock()
+				wrong = append(wrong, fmt.Sprintf("%s in chat %d", kind, id))
+				mu.Unlock()
+			}
+			handled <- struct{}{}
+		}
+	}
+	app.RegisterMediaGroup(record("album", 2))
+	app.RegisterCommand("text", "Text", record("text", 1))
+
+	// Widen the window between routing an album and running its handler.
+	app.Use(func(ctx *tgbotapp.BotContext, next tgbotapp.HandlerFunc) {
+		if ctx.Update.FromChat().ID == 2 {
+			time.Sleep(5 * time.Millisecond)
+		}
+		next(ctx)
+	})
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+	go func() {
+		done <- app.Start(ctx)
+	}()
+
+	// Act
+	// Albums of chat 2 are flushed while texts of chat 1 are handled.
+	for i := range rounds {
+		f.PushUpdate(*newAlbumUpdate(2, i+1, fmt.Sprint("album", i)))
+		for range texts {
+			f.PushMessage(1, "/text")
+			time.Sleep(time.Millisecond)
+		}
+	}
+	for range rounds * (texts + 1) {
+		select {
+		case <-handled:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected all updates to be handled")
+		}
+	}
+	cancel()
+	<-done
+
+	// Assert
+	if len(wrong) != 0 {
+		t.Errorf("Expected every handler to run for its own chat, found %v", wrong)
+	}
+}
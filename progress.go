@@ -0,0 +1,211 @@
+package tgbotapp
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// Default interval between two edits of a progress message.
+	DefaultProgressInterval = 2 * time.Second
+)
+
+// Control the progress message options.
+type ProgressOption func(*Progress)
+
+// Edit the progress message at most edits times per interval.
+func WithProgressRate(edits int, per time.Duration) ProgressOption {
+	return func(p *Progress) {
+		if edits > 0 {
+			p.gap = per / time.Duration(edits)
+		}
+	}
+}
+
+// Render the fraction given to Report as a bar of width cells.
+func WithProgressBar(width int) ProgressOption {
+	return func(p *Progress) {
+		p.barWidth = width
+	}
+}
+
+// Send and edit the progress message with opts, such as ReplyTo or
+// ParseMode. Options only valid for new messages are ignored by the edits.
+func WithProgressSendOptions(opts ...SendOption) ProgressOption {
+	return func(p *Progress) {
+		p.send = newSendOptions(opts)
+	}
+}
+
+// Progress owns a message showing the progress of a long running task.
+// Updates are coalesced so the message is edited at a limited rate, and
+// only when its text changes.
+type Progress struct {
+	h         *HandlerContext
+	messageID int
+	gap       time.Duration
+	barWidth  int
+	send      *sendOptions
+
+	mu       sync.Mutex
+	text     string
+	sent     string
+	last     time.Time
+	timer    *time.Timer
+	finished bool
+
+	// Held while the message is edited.
+	edit sync.Mutex
+}
+
+// Send text as a progress message to the current chat.
+func (h *HandlerContext) StartProgress(text string, opts ...ProgressOption) (*Progress, error) {
+	p := &Progress{
+		h:    h,
+		gap:  DefaultProgressInterval,
+		send: newSendOptions(nil),
+		text: text,
+		sent: text,
+		last: time.Now(),
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if err := p.send.validate(); err != nil {
+		h.LogError("Cannot send progress message.", err)
+		return nil, err
+	}
+
+	msg, err := h.sendWith(p.send.apply(tgbotapi.NewMessage(h.GetChatID(), text)), p.send)
+	if err != nil {
+		h.LogError("Cannot send progress message.", err)
+		return nil, err
+	}
+	p.messageID = msg.MessageID
+
+	return p, nil
+}
+
+func (p *Progress) MessageID() int {
+	return p.messageID
+}
+
+// Show text. The message is edited once the rate allows it.
+func (p *Progress) Update(text string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.finished {
+		return
+	}
+	p.text = text
+
+	if p.timer == nil {
+		wait := max(time.Until(p.last.Add(p.gap)), 0)
+		p.timer = time.AfterFunc(wait, p.flush)
+	}
+}
+
+// Show text with fraction, between 0 and 1, of the task done.
+func (p *Progress) Report(fraction float64, text string) {
+	p.Update(text + "\n" + progressBar(fraction, p.barWidth))
+}
+
+func (p *Progress) flush() {
+	p.edit.Lock()
+	defer p.edit.Unlock()
+
+	p.mu.Lock()
+	p.timer = nil
+	text := p.text
+	if p.finished || text == p.sent {
+		p.mu.Unlock()
+		return
+	}
+	p.last = time.Now()
+	p.mu.Unlock()
+
+	if err := p.editText(text); err != nil {
+		p.h.Logger.WarnContext(p.h.Ctx, "Cannot update progress message.", "error_detail", err)
+		return
+	}
+
+	p.mu.Lock()
+	p.sent = text
+	p.mu.Unlock()
+}
+
+// Replace the progress with the result text.
+func (p *Progress) Done(text string) error {
+	return p.finish(text)
+}
+
+// Log err and replace the progress with text.
+func (p *Progress) Fail(err error, text string) error {
+	p.h.LogError("Task failed.", err)
+	return p.finish(text)
+}
+
+func (p *Progress) finish(text string) error {
+	p.mu.Lock()
+	p.finished = true
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+	p.mu.Unlock()
+
+	// Wait for an edit in progress.
+	p.edit.Lock()
+	defer p.edit.Unlock()
+
+	p.mu.Lock()
+	sent := p.sent
+	p.mu.Unlock()
+
+	if text == sent {
+		return nil
+	}
+	if err := p.editText(text); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.sent = text
+	p.mu.Unlock()
+	return nil
+}
+
+// Edit the message to text. An edit that does not change the message
+// succeeds.
+func (p *Progress) editText(text string) error {
+	edit := tgbotapi.NewEditMessageText(p.h.GetChatID(), p.messageID, text)
+	_, err := p.h.sendWith(p.send.apply(edit), p.send)
+	if isNotModifiedError(err) {
+		return nil
+	}
+	return err
+}
+
+func isNotModifiedError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "message is not modified")
+}
+
+// Render fraction as a bar of width cells followed by a percentage.
+func progressBar(fraction float64, width int) string {
+	fraction = min(max(fraction, 0), 1)
+	percent := fmt.Sprintf("%d%%", int(math.Round(fraction*100)))
+	if width <= 0 {
+		return percent
+	}
+
+	filled := int(math.Round(fraction * float64(width)))
+	return strings.Repeat("█", filled) + strings.Repeat("░", width-filled) + " " + percent
+}
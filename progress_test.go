@@ -0,0 +1,126 @@
+package tgbotapp_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	tgbotapp "github.com/nexoratech2025/go-telegram-bot-app"
+)
+
+func TestProgressShouldCoalesceUpdates(t *testing.T) {
+	// Arrange
+	h, f := newHandlerContext(t)
+	p, err := h.StartProgress("Working...", tgbotapp.WithProgressRate(1, 50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Expected progress message to be sent, found %v", err)
+	}
+
+	// Act
+	for i := range 10 {
+		p.Update(fmt.Sprintf("Step %d", i))
+	}
+	time.Sleep(150 * time.Millisecond)
+
+	// Assert
+	edits, params := lastCall(f, "editMessageText")
+	if edits != 1 {
+		t.Errorf("Expected updates to be coalesced into one edit, found %d", edits)
+	}
+	if text := params.Get("text"); text != "Step 9" {
+		t.Errorf("Expected last update to be shown, found %q", text)
+	}
+	if id := params.Get("message_id"); id != "1" {
+		t.Errorf("Expected progress message to be edited, found %q", id)
+	}
+}
+
+func TestProgressShouldSkipIdenticalText(t *testing.T) {
+	// Arrange
+	h, f := newHandlerContext(t)
+	p, _ := h.StartProgress("Working...", tgbotapp.WithProgressRate(1, time.Millisecond))
+
+	// Act
+	p.Update("Working...")
+	time.Sleep(20 * time.Millisecond)
+	err := p.Done("Working...")
+
+	// Assert
+	if err != nil {
+		t.Errorf("Expected no error, found %v", err)
+	}
+	if edits, _ := lastCall(f, "editMessageText"); edits != 0 {
+		t.Errorf("Expected no edit, found %d", edits)
+	}
+}
+
+func TestProgressShouldRenderBarAndFinalize(t *testing.T) {
+	// Arrange
+	h, f := newHandlerContext(t)
+	p, _ := h.StartProgress("Working...", tgbotapp.WithProgressBar(10), tgbotapp.WithProgressRate(1, time.Millisecond))
+
+	// Act
+	p.Report(0.5, "Uploading")
+	time.Sleep(20 * time.Millisecond)
+	_, params := lastCall(f, "editMessageText")
+	reported := params.Get("text")
+
+	err := p.Fail(errors.New("backend down"), "Upload failed.")
+	p.Update("late")
+	time.Sleep(20 * time.Millisecond)
+
+	// Assert
+	if expected := "Uploading\n█████░░░░░ 50%"; reported != expected {
+		t.Errorf("Expected %q, found %q", expected, reported)
+	}
+	if err != nil {
+		t.Errorf("Expected final edit to succeed, found %v", err)
+	}
+	edits, params := lastCall(f, "editMessageText")
+	if edits != 2 || params.Get("text") != "Upload failed." {
+		t.Errorf("Expected final text after 2 edits, found %d edits, %q", edits, params.Get("text"))
+	}
+}
+
+func TestProgressShouldRetryFailedFinalEdit(t *testing.T) {
+	// Arrange
+	h, f := newHandlerContext(t, tgbotapp.WithRetryPolicy(tgbotapp.RetryPolicy{MaxAttempts: 1}))
+	p, _ := h.StartProgress("Working...")
+	f.FailNextWithRetryAfter("editMessageText", 1)
+
+	// Act
+	failed := p.Done("Finished.")
+	err := p.Done("Finished.")
+
+	// Assert
+	if failed == nil || err != nil {
+		t.Errorf("Expected first edit to fail and second to succeed, found %v, %v", failed, err)
+	}
+	if edits, params := lastCall(f, "editMessageText"); edits != 2 || params.Get("text") != "Finished." {
+		t.Errorf("Expected final text after 2 edits, found %d edits, %q", edits, params.Get("text"))
+	}
+}
+
+func TestStartProgressShouldApplySendOptions(t *testing.T) {
+	// Arrange
+	h, f := newHandlerContext(t)
+
+	// Act
+	p, err := h.StartProgress("<b>Working</b>",
+		tgbotapp.WithProgressSendOptions(tgbotapp.ReplyTo(7), tgbotapp.ParseMode(tgbotapp.ParseModeHTML)))
+	if err == nil {
+		err = p.Done("<b>Done</b>")
+	}
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected progress to be sent and edited, found %v", err)
+	}
+	if _, params := lastCall(f, "sendMessage"); params.Get("reply_to_message_id") != "7" || params.Get("parse_mode") != tgbotapp.ParseModeHTML {
+		t.Errorf("Expected options on the progress message, found %v", params)
+	}
+	if _, params := lastCall(f, "editMessageText"); params.Get("parse_mode") != tgbotapp.ParseModeHTML {
+		t.Errorf("Expected parse mode on the edit, found %v", params)
+	}
+}
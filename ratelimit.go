@@ -0,0 +1,365 @@
+package tgbotapp
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+var (
+	ErrSchedulerClosed = errors.New("Outbound scheduler is closed.")
+)
+
+// Priority of an outbound request. Higher priorities are sent first.
+type Priority int
+
+const (
+	PriorityBulk Priority = iota
+	PriorityNormal
+	PriorityInteractive
+
+	numPriorities = int(PriorityInteractive) + 1
+)
+
+type priorityCtxKey struct{}
+
+// Return ctx carrying the priority used for outbound requests made with it.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityCtxKey{}, p)
+}
+
+// Return the priority carried by ctx, or def if there is none.
+func PriorityFrom(ctx context.Context, def Priority) Priority {
+	if p, ok := ctx.Value(priorityCtxKey{}).(Priority); ok && p >= PriorityBulk && p <= PriorityInteractive {
+		return p
+	}
+	return def
+}
+
+type noRequeueCtxKey struct{}
+
+// Return ctx for a request that cannot be repeated, so the scheduler does not
+// re-queue it after a 429 response.
+func withoutRequeue(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRequeueCtxKey{}, true)
+}
+
+// Telegram flood limits enforced by OutboundScheduler. Zero values disable
+// the corresponding limit.
+type RateLimits struct {
+	// Maximum requests per second over all chats.
+	GlobalPerSecond int
+	// Minimum interval between requests to the same chat.
+	PerChatInterval time.Duration
+	// Maximum requests per minute to the same group or channel.
+	PerGroupPerMinute int
+	// How many times a request is re-queued after a 429 response.
+	MaxRetryAfter int
+}
+
+// Limits documented by Telegram for bots.
+func DefaultRateLimits() RateLimits {
+	return RateLimits{
+		GlobalPerSecond:   30,
+		PerChatInterval:   time.Second,
+		PerGroupPerMinute: 20,
+		MaxRetryAfter:     3,
+	}
+}
+
+type outboundJob struct {
+	ctx      context.Context
+	chatID   int64
+	priority Priority
+	fn       func() error
+	requeue  bool
+	retries  int
+	done     chan error
+}
+
+// OutboundScheduler queues outbound Bot API requests and releases them within
+// Telegram's global, per-chat and per-group limits. Requests wait in one queue
+// per priority; a request for a chat that is still cooling down does not block
+// requests for other chats. 429 responses pause the chat (or all chats, for
+// requests without a chat) for the time Telegram asks for and re-queue the
+// request.
+type OutboundScheduler struct {
+	limits RateLimits
+
+	mu        sync.Mutex
+	queues    [numPriorities][]*outboundJob
+	chatNext  map[int64]time.Time
+	groupSent map[int64][]time.Time
+	sent      []time.Time
+	paused    time.Time
+	closed    bool
+
+	wake chan struct{}
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// Return new running scheduler. Close must be called to stop it.
+func NewOutboundScheduler(limits RateLimits) *OutboundScheduler {
+	s := &OutboundScheduler{
+		limits:    limits,
+		chatNext:  make(map[int64]time.Time),
+		groupSent: make(map[int64][]time.Time),
+		wake:      make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.loop()
+
+	return s
+}
+
+// Do queues fn as a request to chatID and waits until it was executed. Use
+// chatID 0 for requests that are not sent to a chat, such as callback
+// answers; they are only subject to the global limit. The priority is taken
+// from ctx (see WithPriority), defaulting to PriorityNormal.
+func (s *OutboundScheduler) Do(ctx context.Context, chatID int64, fn func() error) error {
+	job := &outboundJob{
+		ctx:      ctx,
+		chatID:   chatID,
+		priority: PriorityFrom(ctx, PriorityNormal),
+		fn:       fn,
+		requeue:  ctx.Value(noRequeueCtxKey{}) == nil,
+		done:     make(chan error, 1),
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrSchedulerClosed
+	}
+	s.queues[job.priority] = append(s.queues[job.priority], job)
+	s.mu.Unlock()
+	s.signal()
+
+	select {
+	case err := <-job.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops the scheduler. Queued requests fail with ErrSchedulerClosed.
+func (s *OutboundScheduler) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.stop)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for p := range s.queues {
+		for _, job := range s.queues[p] {
+			job.done <- ErrSchedulerClosed
+		}
+		s.queues[p] = nil
+	}
+}
+
+func (s *OutboundScheduler) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *OutboundScheduler) loop() {
+	defer s.wg.Done()
+
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		job, wait := s.next(time.Now())
+		if job != nil {
+			s.wg.Add(1)
+			go s.run(job)
+			continue
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+
+		select {
+		case <-s.stop:
+			return
+		case <-s.wake:
+		case <-timer.C:
+		}
+	}
+}
+
+// Pick the next job that may be sent at now, or return how long to wait.
+func (s *OutboundScheduler) next(now time.Time) (*outboundJob, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wait := time.Hour
+
+	if now.Before(s.paused) {
+		return nil, s.paused.Sub(now)
+	}
+
+	if s.limits.GlobalPerSecond > 0 {
+		s.sent = trimWindow(s.sent, now.Add(-time.Second))
+		if len(s.sent) >= s.limits.GlobalPerSecond {
+			return nil, s.sent[0].Add(time.Second).Sub(now)
+		}
+	}
+
+	for p := numPriorities - 1; p >= 0; p-- {
+		// Callers that gave up are not waiting for the result any more.
+		s.queues[p] = slices.DeleteFunc(s.queues[p], func(job *outboundJob) bool {
+			return job.ctx.Err() != nil
+		})
+
+		for i, job := range s.queues[p] {
+			if ready := s.readyAt(job.chatID, now); ready.After(now) {
+				wait = min(wait, ready.Sub(now))
+				continue
+			}
+
+			s.queues[p] = slices.Delete(s.queues[p], i, i+1)
+			s.record(job.chatID, now)
+			return job, 0
+		}
+	}
+
+	return nil, wait
+}
+
+func (s *OutboundScheduler) readyAt(chatID int64, now time.Time) time.Time {
+	if chatID == 0 {
+		return now
+	}
+
+	ready := s.chatNext[chatID]
+
+	if isGroupChat(chatID) && s.limits.PerGroupPerMinute > 0 {
+		sent := trimWindow(s.groupSent[chatID], now.Add(-time.Minute))
+		s.groupSent[chatID] = sent
+		if len(sent) >= s.limits.PerGroupPerMinute {
+			ready = later(ready, sent[0].Add(time.Minute))
+		}
+	}
+
+	return ready
+}
+
+func (s *OutboundScheduler) record(chatID int64, now time.Time) {
+	if s.limits.GlobalPerSecond > 0 {
+		s.sent = append(s.sent, now)
+	}
+
+	if chatID == 0 {
+		return
+	}
+
+	s.chatNext[chatID] = now.Add(s.limits.PerChatInterval)
+	if isGroupChat(chatID) && s.limits.PerGroupPerMinute > 0 {
+		s.groupSent[chatID] = append(s.groupSent[chatID], now)
+	}
+
+	// Drop bookkeeping of chats that are ready again so the maps do not grow forever.
+	if len(s.chatNext) > 1024 {
+		for id, t := range s.chatNext {
+			if t.Before(now) {
+				delete(s.chatNext, id)
+			}
+		}
+		for id, sent := range s.groupSent {
+			if len(trimWindow(sent, now.Add(-time.Minute))) == 0 {
+				delete(s.groupSent, id)
+			}
+		}
+	}
+}
+
+func (s *OutboundScheduler) run(job *outboundJob) {
+	defer s.wg.Done()
+
+	err := job.fn()
+
+	retryAfter := RetryAfter(err)
+	if retryAfter <= 0 {
+		job.done <- err
+		return
+	}
+
+	until := time.Now().Add(retryAfter)
+	requeue := job.requeue && job.retries < s.limits.MaxRetryAfter
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		job.done <- err
+		return
+	}
+	if job.chatID == 0 {
+		s.paused = later(s.paused, until)
+	} else {
+		s.chatNext[job.chatID] = later(s.chatNext[job.chatID], until)
+	}
+	if requeue {
+		job.retries++
+		s.queues[job.priority] = append([]*outboundJob{job}, s.queues[job.priority]...)
+	}
+	s.mu.Unlock()
+	s.signal()
+
+	if !requeue {
+		job.done <- err
+	}
+}
+
+// Return how long Telegram asked to wait before retrying, if err is a flood
+// control error.
+func RetryAfter(err error) time.Duration {
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return time.Duration(apiErr.RetryAfter) * time.Second
+	}
+	return 0
+}
+
+// Group, supergroup and channel ids are negative.
+func isGroupChat(chatID int64) bool {
+	return chatID < 0
+}
+
+func trimWindow(times []time.Time, from time.Time) []time.Time {
+	i := 0
+	for i < len(times) && !times[i].After(from) {
+		i++
+	}
+	return times[i:]
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
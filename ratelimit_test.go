@@ -0,0 +1,148 @@
+package tgbotapp_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	tgbotapp "github.com/nexoratech2025/go-telegram-bot-app"
+)
+
+func TestOutboundSchedulerShouldSpacePerChatRequests(t *testing.T) {
+	// Arrange
+	s := tgbotapp.NewOutboundScheduler(tgbotapp.RateLimits{PerChatInterval: 50 * time.Millisecond})
+	defer s.Close()
+
+	var mu sync.Mutex
+	var times []time.Time
+	send := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		times = append(times, time.Now())
+		return nil
+	}
+
+	// Act
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Do(t.Context(), 1, send)
+		}()
+	}
+	wg.Wait()
+
+	// Assert
+	for i := 1; i < len(times); i++ {
+		if d := times[i].Sub(times[i-1]); d < 45*time.Millisecond {
+			t.Errorf("Expected requests to the same chat to be spaced, found %v", d)
+		}
+	}
+}
+
+func TestOutboundSchedulerShouldNotDelayOtherChats(t *testing.T) {
+	// Arrange
+	s := tgbotapp.NewOutboundScheduler(tgbotapp.RateLimits{PerChatInterval: time.Second})
+	defer s.Close()
+
+	s.Do(t.Context(), 1, func() error { return nil })
+
+	// Act
+	start := time.Now()
+	s.Do(t.Context(), 2, func() error { return nil })
+
+	// Assert
+	if d := time.Since(start); d > 100*time.Millisecond {
+		t.Errorf("Expected request to another chat to be sent immediately, waited %v", d)
+	}
+}
+
+func TestOutboundSchedulerShouldSendHigherPriorityFirst(t *testing.T) {
+	// Arrange
+	s := tgbotapp.NewOutboundScheduler(tgbotapp.RateLimits{PerChatInterval: 50 * time.Millisecond})
+	defer s.Close()
+
+	// Occupy the chat so the following requests have to queue.
+	s.Do(t.Context(), 1, func() error { return nil })
+
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) func() error {
+		return func() error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+			return nil
+		}
+	}
+
+	// Act
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		s.Do(tgbotapp.WithPriority(t.Context(), tgbotapp.PriorityBulk), 1, record("bulk"))
+	}()
+	time.Sleep(10 * time.Millisecond)
+	go func() {
+		defer wg.Done()
+		s.Do(tgbotapp.WithPriority(t.Context(), tgbotapp.PriorityInteractive), 1, record("interactive"))
+	}()
+	wg.Wait()
+
+	// Assert
+	if len(order) != 2 || order[0] != "interactive" {
+		t.Errorf("Expected interactive request first, found %v", order)
+	}
+}
+
+func TestOutboundSchedulerShouldHonorRetryAfter(t *testing.T) {
+	// Arrange
+	s := tgbotapp.NewOutboundScheduler(tgbotapp.RateLimits{MaxRetryAfter: 1})
+	defer s.Close()
+
+	calls := 0
+	fn := func() error {
+		calls++
+		if calls == 1 {
+			return &tgbotapi.Error{Code: 429, Message: "Too Many Requests", ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 1}}
+		}
+		return nil
+	}
+
+	// Act
+	start := time.Now()
+	err := s.Do(t.Context(), 1, fn)
+
+	// Assert
+	if err != nil {
+		t.Errorf(expectsNoError, err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected %d calls, found %d", 2, calls)
+	}
+	if d := time.Since(start); d < time.Second {
+		t.Errorf("Expected retry after at least 1s, retried after %v", d)
+	}
+}
+
+func TestOutboundSchedulerShouldReturnWhenContextIsCancelled(t *testing.T) {
+	// Arrange
+	s := tgbotapp.NewOutboundScheduler(tgbotapp.RateLimits{PerChatInterval: time.Hour})
+	defer s.Close()
+	s.Do(t.Context(), 1, func() error { return nil })
+
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+
+	// Act
+	err := s.Do(ctx, 1, func() error { return nil })
+
+	// Assert
+	if err != context.DeadlineExceeded {
+		t.Errorf(expectsErrorType, context.DeadlineExceeded, err)
+	}
+}
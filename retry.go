@@ -0,0 +1,227 @@
+package tgbotapp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"syscall"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Retry behaviour for outbound Bot API requests.
+type RetryPolicy struct {
+	// Total number of attempts, including the first one. Values below 2 disable retries.
+	MaxAttempts int
+	// Wait before the second attempt. It is multiplied by Multiplier after every attempt.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	// Fraction of the backoff that is randomized, between 0 and 1.
+	Jitter float64
+	// Decide whether err is worth retrying. Defaults to IsRetryable.
+	Retryable func(err error) bool
+	// Also retry requests that are not idempotent, such as sendMessage, when
+	// the request may have reached Telegram. This can deliver duplicates.
+	RetryUnsafe bool
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.5,
+	}
+}
+
+// Policy that never retries.
+func NoRetry() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 1}
+}
+
+type retryCtxKey struct{}
+
+// Return ctx carrying a retry policy that overrides the application's policy
+// for outbound requests made with it.
+func WithCallRetry(ctx context.Context, p RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryCtxKey{}, p)
+}
+
+func retryPolicyFrom(ctx context.Context) (RetryPolicy, bool) {
+	p, ok := ctx.Value(retryCtxKey{}).(RetryPolicy)
+	return p, ok
+}
+
+// Do calls fn until it succeeds, returns an error that is not retryable, the
+// attempts are used up or ctx is done. idempotent tells whether fn is safe to
+// repeat after it may have reached Telegram.
+func (p RetryPolicy) Do(ctx context.Context, idempotent bool, fn func() error) error {
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+
+	backoff := p.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.MaxAttempts || !retryable(err) {
+			return err
+		}
+
+		if !idempotent && !p.RetryUnsafe && !notDelivered(err) {
+			return err
+		}
+
+		if !sleepContext(ctx, max(p.jitter(backoff), RetryAfter(err))) {
+			return err
+		}
+
+		backoff = p.next(backoff)
+	}
+}
+
+func (p RetryPolicy) next(backoff time.Duration) time.Duration {
+	if p.Multiplier > 0 {
+		backoff = time.Duration(float64(backoff) * p.Multiplier)
+	}
+	if p.MaxBackoff > 0 {
+		backoff = min(backoff, p.MaxBackoff)
+	}
+	return backoff
+}
+
+func (p RetryPolicy) jitter(backoff time.Duration) time.Duration {
+	j := min(max(p.Jitter, 0), 1)
+	if j == 0 || backoff <= 0 {
+		return backoff
+	}
+	return time.Duration(float64(backoff) * (1 - j*rand.Float64()))
+}
+
+// IsRetryable reports whether err is a transient failure: a network error, a
+// Telegram server error or a flood control error.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code >= 500 || isFloodError(apiErr)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	return errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// Whether err proves that the request had no effect: it never left the
+// machine, or Telegram rejected it because of flood control.
+func notDelivered(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) && isFloodError(apiErr) {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// tgbotapi does not set the error code for failed uploads, so flood errors
+// are also recognized by their retry_after parameter.
+func isFloodError(err *tgbotapi.Error) bool {
+	return err.Code == 429 || err.RetryAfter > 0
+}
+
+// Whether repeating c cannot cause a duplicate effect.
+func isIdempotent(c tgbotapi.Chattable) bool {
+	switch c.(type) {
+	case tgbotapi.EditMessageTextConfig,
+		tgbotapi.EditMessageCaptionConfig,
+		tgbotapi.EditMessageReplyMarkupConfig,
+		tgbotapi.EditMessageMediaConfig,
+		tgbotapi.EditMessageLiveLocationConfig,
+		tgbotapi.StopMessageLiveLocationConfig,
+		tgbotapi.DeleteMessageConfig,
+		tgbotapi.CallbackConfig,
+		tgbotapi.ChatActionConfig,
+		tgbotapi.SetMyCommandsConfig,
+		tgbotapi.DeleteMyCommandsConfig,
+		tgbotapi.FileConfig,
+		tgbotapi.PinChatMessageConfig,
+		tgbotapi.UnpinChatMessageConfig:
+		return true
+	}
+	return false
+}
+
+// Whether c can be sent more than once. Uploads from an io.Reader consume
+// the reader, so they cannot.
+func isRepeatable(c tgbotapi.Chattable) bool {
+	var files []tgbotapi.RequestFileData
+
+	switch c := c.(type) {
+	case tgbotapi.PhotoConfig:
+		files = append(files, c.File, c.Thumb)
+	case tgbotapi.DocumentConfig:
+		files = append(files, c.File, c.Thumb)
+	case tgbotapi.VideoConfig:
+		files = append(files, c.File, c.Thumb)
+	case tgbotapi.AnimationConfig:
+		files = append(files, c.File, c.Thumb)
+	case tgbotapi.AudioConfig:
+		files = append(files, c.File, c.Thumb)
+	case tgbotapi.VoiceConfig:
+		files = append(files, c.File, c.Thumb)
+	case tgbotapi.VideoNoteConfig:
+		files = append(files, c.File, c.Thumb)
+	case tgbotapi.StickerConfig:
+		files = append(files, c.File)
+	case tgbotapi.MediaGroupConfig:
+		for _, m := range c.Media {
+			files = append(files, inputMediaFiles(m)...)
+		}
+	case tgbotapi.EditMessageMediaConfig:
+		files = append(files, inputMediaFiles(c.Media)...)
+	}
+
+	for _, f := range files {
+		switch f.(type) {
+		case tgbotapi.FileReader, *tgbotapi.FileReader:
+			return false
+		}
+	}
+
+	return true
+}
+
+func inputMediaFiles(m interface{}) []tgbotapi.RequestFileData {
+	switch m := m.(type) {
+	case tgbotapi.InputMediaPhoto:
+		return []tgbotapi.RequestFileData{m.Media}
+	case tgbotapi.InputMediaVideo:
+		return []tgbotapi.RequestFileData{m.Media, m.Thumb}
+	case tgbotapi.InputMediaAnimation:
+		return []tgbotapi.RequestFileData{m.Media, m.Thumb}
+	case tgbotapi.InputMediaAudio:
+		return []tgbotapi.RequestFileData{m.Media, m.Thumb}
+	case tgbotapi.InputMediaDocument:
+		return []tgbotapi.RequestFileData{m.Media, m.Thumb}
+	}
+	return nil
+}
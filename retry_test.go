@@ -0,0 +1,99 @@
+package tgbotapp_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	tgbotapp "github.com/nexoratech2025/go-telegram-bot-app"
+)
+
+var (
+	serverError = &tgbotapi.Error{Code: 502, Message: "Bad Gateway"}
+	badRequest  = &tgbotapi.Error{Code: 400, Message: "Bad Request: chat not found"}
+	dialError   = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+)
+
+func fastRetry(attempts int) tgbotapp.RetryPolicy {
+	return tgbotapp.RetryPolicy{
+		MaxAttempts:    attempts,
+		InitialBackoff: time.Millisecond,
+		Multiplier:     2,
+		Jitter:         0.5,
+	}
+}
+
+func failing(errs ...error) (func() error, *int) {
+	calls := 0
+	return func() error {
+		calls++
+		if calls <= len(errs) {
+			return errs[calls-1]
+		}
+		return nil
+	}, &calls
+}
+
+func TestRetryPolicyShouldRetryTransientErrors(t *testing.T) {
+	fn, calls := failing(serverError, serverError)
+
+	err := fastRetry(3).Do(t.Context(), true, fn)
+
+	if err != nil {
+		t.Errorf(expectsNoError, err)
+	}
+	if *calls != 3 {
+		t.Errorf("Expected %d calls, found %d", 3, *calls)
+	}
+}
+
+func TestRetryPolicyShouldNotRetryClientErrors(t *testing.T) {
+	fn, calls := failing(badRequest)
+
+	err := fastRetry(3).Do(t.Context(), true, fn)
+
+	if !errors.Is(err, badRequest) {
+		t.Errorf(expectsErrorType, badRequest, err)
+	}
+	if *calls != 1 {
+		t.Errorf("Expected %d calls, found %d", 1, *calls)
+	}
+}
+
+func TestRetryPolicyShouldSkipUnsafeRequestsByDefault(t *testing.T) {
+	fn, calls := failing(serverError)
+
+	fastRetry(3).Do(t.Context(), false, fn)
+
+	if *calls != 1 {
+		t.Errorf("Expected %d calls, found %d", 1, *calls)
+	}
+}
+
+func TestRetryPolicyShouldRetryUnsafeRequestsThatWereNotDelivered(t *testing.T) {
+	fn, calls := failing(dialError)
+
+	err := fastRetry(3).Do(t.Context(), false, fn)
+
+	if err != nil {
+		t.Errorf(expectsNoError, err)
+	}
+	if *calls != 2 {
+		t.Errorf("Expected %d calls, found %d", 2, *calls)
+	}
+}
+
+func TestRetryPolicyShouldStopAfterMaxAttempts(t *testing.T) {
+	fn, calls := failing(serverError, serverError, serverError, serverError)
+
+	err := fastRetry(2).Do(t.Context(), true, fn)
+
+	if err == nil {
+		t.Error(expectsError)
+	}
+	if *calls != 2 {
+		t.Errorf("Expected %d calls, found %d", 2, *calls)
+	}
+}
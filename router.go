@@ -14,6 +14,8 @@ const (
 	CallbackHandler
 	MessageHandler
 	DocumentHandler
+	PromptHandler
+	TimeoutHandler
 )
 
 func (h HandlerAction) String() string {
@@ -26,6 +28,10 @@ func (h HandlerAction) String() string {
 		return "Message State Handler"
 	case DocumentHandler:
 		return "Document Handler"
+	case PromptHandler:
+		return "Prompt Handler"
+	case TimeoutHandler:
+		return "Timeout Handler"
 	default:
 		return "Unknown Handler"
 	}
@@ -92,6 +98,14 @@ func RouterWithDefault(router Router, defaultFunc HandlerFunc) Middleware {
 			context.Params = strings.Split(context.Update.Message.CommandArguments(), CommandDelimiter)
 
 		case context.Update.Message != nil:
+			if _, ok := context.GetData(MediaGroupDataKey); ok {
+				h, ok := router.GetHandler(MediaGroupHandlerName, DocumentHandler)
+				if ok {
+					f = h.Func
+					break
+				}
+			}
+
 			if hasDocument(context.Update.Message) {
 				docType := getDocumentType(context.Update.Message)
 				h, ok := router.GetHandler(docType, DocumentHandler)
@@ -0,0 +1,317 @@
+package tgbotapp
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+var (
+	ErrInvalidParseMode = errors.New("Invalid parse mode.")
+)
+
+// Control how a message is sent or edited. Options that do not apply to a
+// request, such as Silent for an edit, are ignored.
+type SendOption func(*sendOptions)
+
+type sendOptions struct {
+	replyTo     int
+	silent      bool
+	protect     bool
+	threadID    int
+	noPreview   bool
+	keyboard    interface{}
+	hasKeyboard bool
+	parseMode   string
+}
+
+func newSendOptions(opts []SendOption) *sendOptions {
+	o := &sendOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Send the message as a reply to messageID.
+func ReplyTo(messageID int) SendOption {
+	return func(o *sendOptions) {
+		o.replyTo = messageID
+	}
+}
+
+// Send the message without notification sound.
+func Silent() SendOption {
+	return func(o *sendOptions) {
+		o.silent = true
+	}
+}
+
+// Protect the message from forwarding and saving.
+func Protect() SendOption {
+	return func(o *sendOptions) {
+		o.protect = true
+	}
+}
+
+// Send the message to a topic of a forum supergroup.
+func ThreadID(threadID int) SendOption {
+	return func(o *sendOptions) {
+		o.threadID = threadID
+	}
+}
+
+// Disable the link preview of a text message.
+func NoPreview() SendOption {
+	return func(o *sendOptions) {
+		o.noPreview = true
+	}
+}
+
+// Attach a reply markup. Edits only accept tgbotapi.InlineKeyboardMarkup.
+func Keyboard(markup interface{}) SendOption {
+	return func(o *sendOptions) {
+		o.keyboard = markup
+		o.hasKeyboard = true
+	}
+}
+
+// Parse the text or caption with mode: ParseModeHTML, ParseModeMarkdown or
+// ParseModeMarkdownV2.
+func ParseMode(mode string) SendOption {
+	return func(o *sendOptions) {
+		o.parseMode = mode
+	}
+}
+
+func (o *sendOptions) validate() error {
+	switch o.parseMode {
+	case "", ParseModeHTML, ParseModeMarkdown, ParseModeMarkdownV2:
+		return nil
+	}
+	return ErrInvalidParseMode
+}
+
+func (o *sendOptions) applyChat(c tgbotapi.BaseChat) tgbotapi.BaseChat {
+	if o.replyTo != 0 {
+		c.ReplyToMessageID = o.replyTo
+	}
+	if o.silent {
+		c.DisableNotification = true
+	}
+	if o.hasKeyboard {
+		c.ReplyMarkup = o.keyboard
+	}
+	return c
+}
+
+func (o *sendOptions) applyEdit(e tgbotapi.BaseEdit) tgbotapi.BaseEdit {
+	if markup, ok := o.keyboard.(tgbotapi.InlineKeyboardMarkup); ok && o.hasKeyboard {
+		e.ReplyMarkup = &markup
+	}
+	return e
+}
+
+func (o *sendOptions) mode(current string) string {
+	if o.parseMode != "" {
+		return o.parseMode
+	}
+	return current
+}
+
+// Return c with the options applied.
+func (o *sendOptions) apply(c tgbotapi.Chattable) tgbotapi.Chattable {
+	switch c := c.(type) {
+	case tgbotapi.MessageConfig:
+		c.BaseChat = o.applyChat(c.BaseChat)
+		c.ParseMode = o.mode(c.ParseMode)
+		c.DisableWebPagePreview = c.DisableWebPagePreview || o.noPreview
+		return c
+	case tgbotapi.PhotoConfig:
+		c.BaseChat = o.applyChat(c.BaseChat)
+		c.ParseMode = o.mode(c.ParseMode)
+		return c
+	case tgbotapi.DocumentConfig:
+		c.BaseChat = o.applyChat(c.BaseChat)
+		c.ParseMode = o.mode(c.ParseMode)
+		return c
+	case tgbotapi.VideoConfig:
+		c.BaseChat = o.applyChat(c.BaseChat)
+		c.ParseMode = o.mode(c.ParseMode)
+		return c
+	case tgbotapi.AnimationConfig:
+		c.BaseChat = o.applyChat(c.BaseChat)
+		c.ParseMode = o.mode(c.ParseMode)
+		return c
+	case tgbotapi.AudioConfig:
+		c.BaseChat = o.applyChat(c.BaseChat)
+		c.ParseMode = o.mode(c.ParseMode)
+		return c
+	case tgbotapi.VoiceConfig:
+		c.BaseChat = o.applyChat(c.BaseChat)
+		c.ParseMode = o.mode(c.ParseMode)
+		return c
+	case tgbotapi.VideoNoteConfig:
+		c.BaseChat = o.applyChat(c.BaseChat)
+		return c
+	case tgbotapi.StickerConfig:
+		c.BaseChat = o.applyChat(c.BaseChat)
+		return c
+	case tgbotapi.LocationConfig:
+		c.BaseChat = o.applyChat(c.BaseChat)
+		return c
+	case tgbotapi.VenueConfig:
+		c.BaseChat = o.applyChat(c.BaseChat)
+		return c
+	case tgbotapi.ContactConfig:
+		c.BaseChat = o.applyChat(c.BaseChat)
+		return c
+	case tgbotapi.SendPollConfig:
+		c.BaseChat = o.applyChat(c.BaseChat)
+		return c
+	case tgbotapi.MediaGroupConfig:
+		if o.replyTo != 0 {
+			c.ReplyToMessageID = o.replyTo
+		}
+		c.DisableNotification = c.DisableNotification || o.silent
+		return c
+	case tgbotapi.EditMessageTextConfig:
+		c.BaseEdit = o.applyEdit(c.BaseEdit)
+		c.ParseMode = o.mode(c.ParseMode)
+		c.DisableWebPagePreview = c.DisableWebPagePreview || o.noPreview
+		return c
+	case tgbotapi.EditMessageCaptionConfig:
+		c.BaseEdit = o.applyEdit(c.BaseEdit)
+		c.ParseMode = o.mode(c.ParseMode)
+		return c
+	case tgbotapi.EditMessageReplyMarkupConfig:
+		c.BaseEdit = o.applyEdit(c.BaseEdit)
+		return c
+	}
+	return c
+}
+
+// Parameters tgbotapi does not know about.
+func (o *sendOptions) extra(c tgbotapi.Chattable) url.Values {
+	switch c.(type) {
+	case tgbotapi.EditMessageTextConfig, tgbotapi.EditMessageCaptionConfig, tgbotapi.EditMessageReplyMarkupConfig:
+		return nil
+	}
+
+	extra := url.Values{}
+	if o.protect {
+		extra.Set("protect_content", "true")
+	}
+	if o.threadID != 0 {
+		extra.Set("message_thread_id", strconv.Itoa(o.threadID))
+	}
+	return extra
+}
+
+// Return api, or a copy of it adding extra to the parameters of every request.
+func apiWithParams(api *tgbotapi.BotAPI, extra url.Values) *tgbotapi.BotAPI {
+	if len(extra) == 0 || api == nil {
+		return api
+	}
+
+	clone := *api
+	clone.Client = &extraParamsClient{client: api.Client, extra: extra}
+	return &clone
+}
+
+// HTTP client adding form fields to Bot API requests.
+type extraParamsClient struct {
+	client tgbotapi.HTTPClient
+	extra  url.Values
+}
+
+func (c *extraParamsClient) Do(req *http.Request) (*http.Response, error) {
+	mediaType, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil {
+		return c.client.Do(req)
+	}
+
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		b, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		values, err := url.ParseQuery(string(b))
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range c.extra {
+			values[k] = slices.Clone(v)
+		}
+
+		body := values.Encode()
+		req.Body = io.NopCloser(strings.NewReader(body))
+		req.ContentLength = int64(len(body))
+
+	case strings.HasPrefix(mediaType, "multipart/"):
+		req.Body = c.rewriteMultipart(req.Body, params["boundary"])
+		req.ContentLength = -1
+	}
+
+	return c.client.Do(req)
+}
+
+// Stream the parts of body followed by the extra fields, keeping the boundary.
+func (c *extraParamsClient) rewriteMultipart(body io.ReadCloser, boundary string) io.ReadCloser {
+	r, w := io.Pipe()
+
+	go func() {
+		defer body.Close()
+
+		mr := multipart.NewReader(body, boundary)
+		mw := multipart.NewWriter(w)
+		if err := mw.SetBoundary(boundary); err != nil {
+			w.CloseWithError(err)
+			return
+		}
+
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				w.CloseWithError(err)
+				return
+			}
+
+			dst, err := mw.CreatePart(part.Header)
+			if err == nil {
+				_, err = io.Copy(dst, part)
+			}
+			if err != nil {
+				w.CloseWithError(err)
+				return
+			}
+		}
+
+		for k, vs := range c.extra {
+			for _, v := range vs {
+				if err := mw.WriteField(k, v); err != nil {
+					w.CloseWithError(err)
+					return
+				}
+			}
+		}
+
+		w.CloseWithError(mw.Close())
+	}()
+
+	return r
+}
@@ -0,0 +1,140 @@
+package tgbotapp_test
+
+import (
+	"errors"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	tgbotapp "github.com/nexoratech2025/go-telegram-bot-app"
+	"github.com/nexoratech2025/go-telegram-bot-app/format"
+)
+
+func TestSendTextShouldApplyOptions(t *testing.T) {
+	// Arrange
+	h, f := newHandlerContext(t)
+
+	// Act
+	_, err := h.SendText("hi",
+		tgbotapp.ReplyTo(7),
+		tgbotapp.Silent(),
+		tgbotapp.Protect(),
+		tgbotapp.ThreadID(3),
+		tgbotapp.NoPreview(),
+		tgbotapp.ParseMode(tgbotapp.ParseModeHTML),
+	)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected message to be sent, found %v", err)
+	}
+
+	expected := map[string]string{
+		"reply_to_message_id":      "7",
+		"disable_notification":     "true",
+		"protect_content":          "true",
+		"message_thread_id":        "3",
+		"disable_web_page_preview": "true",
+		"parse_mode":               tgbotapp.ParseModeHTML,
+	}
+	_, params := lastCall(f, "sendMessage")
+	for k, v := range expected {
+		if found := params.Get(k); found != v {
+			t.Errorf("Expected %s to be %q, found %q", k, v, found)
+		}
+	}
+}
+
+func TestSendDocumentShouldApplyOptionsToUploads(t *testing.T) {
+	// Arrange
+	h, f := newHandlerContext(t)
+
+	// Act
+	_, err := h.SendDocument(tgbotapi.FileBytes{Name: "a.txt", Bytes: []byte("a")}, "caption",
+		tgbotapp.Protect(), tgbotapp.ThreadID(3), tgbotapp.ReplyTo(7))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected document to be sent, found %v", err)
+	}
+
+	_, params := lastCall(f, "sendDocument")
+	if params.Get("protect_content") != "true" || params.Get("message_thread_id") != "3" {
+		t.Errorf("Expected extra upload parameters, found %v", params)
+	}
+	if params.Get("reply_to_message_id") != "7" || params.Get("caption") != "caption" {
+		t.Errorf("Expected upload parameters to be kept, found %v", params)
+	}
+}
+
+func TestEditMessageTextShouldApplyKeyboard(t *testing.T) {
+	// Arrange
+	h, f := newHandlerContext(t)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("ok", "ok")),
+	)
+
+	// Act
+	err := h.EditMessageText("edited", 5, tgbotapp.Keyboard(keyboard), tgbotapp.Protect())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected message to be edited, found %v", err)
+	}
+
+	_, params := lastCall(f, "editMessageText")
+	if params.Get("reply_markup") == "" {
+		t.Errorf("Expected reply markup, found %v", params)
+	}
+	if params.Has("protect_content") {
+		t.Errorf("Expected send only options to be ignored, found %v", params)
+	}
+}
+
+func TestEditFormattedShouldNotSetParseModeForEntities(t *testing.T) {
+	// Arrange
+	h, f := newHandlerContext(t)
+	text := format.Entities(format.Bold("edited"))
+
+	// Act
+	err := h.EditFormatted(text, 5, tgbotapp.ParseMode(tgbotapp.ParseModeHTML))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected message to be edited, found %v", err)
+	}
+
+	_, params := lastCall(f, "editMessageText")
+	if params.Get("parse_mode") != "" || params.Get("entities") == "" {
+		t.Errorf("Expected entities without parse mode, found %v", params)
+	}
+}
+
+func TestSendMessageShouldAcceptParseMode(t *testing.T) {
+	// Arrange
+	h, f := newHandlerContext(t)
+
+	// Act
+	_, err := h.SendMessage("<b>hi</b>", tgbotapp.ParseModeHTML)
+
+	// Assert
+	if _, params := lastCall(f, "sendMessage"); err != nil || params.Get("parse_mode") != tgbotapp.ParseModeHTML {
+		t.Errorf("Expected HTML message, found %v, %v", params, err)
+	}
+}
+
+func TestSendMessageShouldRejectInvalidParseMode(t *testing.T) {
+	// Arrange
+	h, f := newHandlerContext(t)
+
+	// Act
+	_, err := h.SendMessage("hi", "Plain")
+	_, textErr := h.SendText("hi", tgbotapp.ParseMode("Plain"))
+
+	// Assert
+	if !errors.Is(err, tgbotapp.ErrInvalidParseMode) || !errors.Is(textErr, tgbotapp.ErrInvalidParseMode) {
+		t.Errorf("Expected ErrInvalidParseMode, found %v, %v", err, textErr)
+	}
+	if calls := len(f.CallsTo("sendMessage")); calls != 0 {
+		t.Errorf("Expected no message to be sent, found %d", calls)
+	}
+}
@@ -0,0 +1,137 @@
+package tgbotapp_test
+
+import (
+	"log/slog"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	tgbotapp "github.com/nexoratech2025/go-telegram-bot-app"
+	"github.com/nexoratech2025/go-telegram-bot-app/format"
+	"github.com/nexoratech2025/go-telegram-bot-app/testutil"
+)
+
+// Return a handler context of chat 1 on a fake Bot API, also serving its
+// files. opts are applied after a short retry policy.
+func newHandlerContext(t *testing.T, opts ...tgbotapp.OptionFunc) (*tgbotapp.HandlerContext, *testutil.FakeBotAPI) {
+	t.Helper()
+
+	f := testutil.NewFakeBotAPI(t)
+
+	opts = append([]tgbotapp.OptionFunc{func(a *tgbotapp.Application) {
+		a.Logger = slog.Default()
+	}, tgbotapp.WithRetryPolicy(tgbotapp.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}),
+		tgbotapp.WithFileEndpoint(f.FileEndpoint())}, opts...)
+
+	app := tgbotapp.New(f.NewBotAPI(t), opts...)
+
+	ctx := tgbotapp.NewBotContext(t.Context(), app, newChatUpdate(1, ""))
+	return tgbotapp.NewHandlerContext(ctx, "test"), f
+}
+
+// Return the number of calls to method and the parameters of the last one.
+func lastCall(f *testutil.FakeBotAPI, method string) (int, url.Values) {
+	calls := f.CallsTo(method)
+	if len(calls) == 0 {
+		return 0, nil
+	}
+	return len(calls), calls[len(calls)-1].Params
+}
+
+func TestSendPhotoShouldAcceptFileIDAndReturnMessage(t *testing.T) {
+	// Arrange
+	h, f := newHandlerContext(t)
+
+	// Act
+	msg, err := h.SendPhoto(tgbotapi.FileID("AgAC"), "caption")
+
+	// Assert
+	if err != nil || msg.MessageID != 1 || msg.Caption != "caption" {
+		t.Errorf("Expected sent message 1, found %+v, %v", msg, err)
+	}
+	if calls := len(f.CallsTo("sendPhoto")); calls != 1 {
+		t.Errorf("Expected one sendPhoto call, found %d", calls)
+	}
+}
+
+func TestSendLocationAndMessageShouldReturnSentMessages(t *testing.T) {
+	// Arrange
+	h, _ := newHandlerContext(t)
+
+	// Act
+	location, locationErr := h.SendLocation(1.5, 2.5)
+	messages, messageErr := h.SendMessage("hi")
+
+	// Assert
+	if locationErr != nil || location.MessageID != 1 {
+		t.Errorf("Expected sent location 1, found %d, %v", location.MessageID, locationErr)
+	}
+	if messageErr != nil || len(messages) != 1 || messages[0].MessageID != 2 {
+		t.Errorf("Expected one sent message 2, found %+v, %v", messages, messageErr)
+	}
+}
+
+func TestSendDocumentShouldRetryRepeatableUploads(t *testing.T) {
+	// Arrange
+	h, f := newHandlerContext(t)
+	f.FailNextWithRetryAfter("sendDocument", 1)
+
+	// Act
+	_, err := h.SendDocument(tgbotapi.FileBytes{Name: "a.txt", Bytes: []byte("a")}, "")
+
+	// Assert
+	if calls := len(f.CallsTo("sendDocument")); err != nil || calls != 2 {
+		t.Errorf("Expected upload to be retried once, found %d calls, %v", calls, err)
+	}
+}
+
+func TestSendDocumentShouldNotRetryReaderUploads(t *testing.T) {
+	// Arrange
+	h, f := newHandlerContext(t)
+	f.FailNextWithRetryAfter("sendDocument", 1)
+
+	// Act
+	_, err := h.SendDocument(tgbotapi.FileReader{Name: "a.txt", Reader: strings.NewReader("a")}, "")
+
+	// Assert
+	if calls := len(f.CallsTo("sendDocument")); err == nil || calls != 1 {
+		t.Errorf("Expected consumed reader not to be sent again, found %d calls, %v", calls, err)
+	}
+}
+
+func TestSendFormattedShouldSplitEntityTexts(t *testing.T) {
+	// Arrange
+	h, f := newHandlerContext(t, tgbotapp.WithMessageSplitting(10))
+	text := format.Entities(format.Bold("bold words here"))
+
+	// Act
+	sent, err := h.SendFormatted(text)
+
+	// Assert
+	n, params := lastCall(f, "sendMessage")
+	if err != nil || n != 2 || len(sent) != 2 {
+		t.Fatalf("Expected %d messages, found %d sent and %d returned, %v", 2, n, len(sent), err)
+	}
+	if params.Get("text") != "words here" || !strings.Contains(params.Get("entities"), `"offset":0,"length":10`) {
+		t.Errorf("Expected last chunk with its entity, found %v", params)
+	}
+}
+
+func TestSendMessageShouldNotReportFailuresToTheChat(t *testing.T) {
+	// Arrange
+	h, f := newHandlerContext(t)
+	f.FailNext("sendMessage", 403, "Forbidden: bot was blocked by the user")
+
+	// Act
+	_, err := h.SendMessage("hi")
+
+	// Assert
+	if err == nil {
+		t.Error("Expected send error")
+	}
+	if calls := len(f.CallsTo("sendMessage")); calls != 1 {
+		t.Errorf("Expected %d sendMessage call, found %d", 1, calls)
+	}
+}
@@ -0,0 +1,210 @@
+package tgbotapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Sender makes Bot API requests. All outbound requests of the library go
+// through the application's sender, so it can be decorated or faked. The
+// exceptions are receiving updates and downloading file contents, which use
+// the application's BotAPI and its HTTP client directly.
+//
+// Senders must add ExtraParams of the context to their requests.
+type Sender interface {
+	// Make request c. chatID is the chat c is sent to, or 0 for requests
+	// that are not sent to a chat.
+	Request(ctx context.Context, chatID int64, c tgbotapi.Chattable) (*tgbotapi.APIResponse, error)
+}
+
+type SenderFunc func(ctx context.Context, chatID int64, c tgbotapi.Chattable) (*tgbotapi.APIResponse, error)
+
+func (f SenderFunc) Request(ctx context.Context, chatID int64, c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	return f(ctx, chatID, c)
+}
+
+// SenderMiddleware decorates a sender.
+type SenderMiddleware func(next Sender) Sender
+
+// Return s decorated with middlewares. The first middleware is the outermost.
+func ChainSender(s Sender, middlewares ...SenderMiddleware) Sender {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		s = middlewares[i](s)
+	}
+	return s
+}
+
+// Sender making requests with a tgbotapi.BotAPI. Failed uploads report the
+// error code of the response, which tgbotapi leaves out.
+type BotSender struct {
+	API *tgbotapi.BotAPI
+}
+
+func NewBotSender(api *tgbotapi.BotAPI) *BotSender {
+	return &BotSender{API: api}
+}
+
+func (s *BotSender) Request(ctx context.Context, chatID int64, c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	res, err := apiWithParams(s.API, ExtraParams(ctx)).Request(c)
+
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == 0 && res != nil {
+		apiErr.Code = res.ErrorCode
+	}
+	return res, err
+}
+
+// Retry requests with p, or the policy carried by the context of a request
+// (see WithCallRetry). Requests that cannot be repeated are never retried, and
+// neither are flood waits a RateLimitSender already retried.
+func RetrySender(p RetryPolicy) SenderMiddleware {
+	return func(next Sender) Sender {
+		return SenderFunc(func(ctx context.Context, chatID int64, c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+			policy, ok := retryPolicyFrom(ctx)
+			if !ok {
+				policy = p
+			}
+			if !isRepeatable(c) {
+				policy = NoRetry()
+			}
+
+			retryable := policy.Retryable
+			if retryable == nil {
+				retryable = IsRetryable
+			}
+			policy.Retryable = func(err error) bool {
+				var flood *scheduledFloodError
+				return !errors.As(err, &flood) && retryable(err)
+			}
+
+			var res *tgbotapi.APIResponse
+			err := policy.Do(ctx, isIdempotent(c), func() (err error) {
+				res, err = next.Request(ctx, chatID, c)
+				return err
+			})
+			return res, err
+		})
+	}
+}
+
+// Flood wait error the outbound scheduler gave up on after re-queuing the
+// request RateLimits.MaxRetryAfter times.
+type scheduledFloodError struct {
+	err error
+}
+
+func (e *scheduledFloodError) Error() string { return e.err.Error() }
+func (e *scheduledFloodError) Unwrap() error { return e.err }
+
+// Queue requests in scheduler s, at the priority carried by their context.
+// The scheduler owns flood waits: it re-queues requests after 429 responses,
+// and RetrySender does not retry them again.
+func RateLimitSender(s *OutboundScheduler) SenderMiddleware {
+	return func(next Sender) Sender {
+		return SenderFunc(func(ctx context.Context, chatID int64, c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+			if !isRepeatable(c) {
+				ctx = withoutRequeue(ctx)
+			}
+
+			var res *tgbotapi.APIResponse
+			err := s.Do(ctx, chatID, func() (err error) {
+				res, err = next.Request(ctx, chatID, c)
+				return err
+			})
+			if RetryAfter(err) > 0 {
+				return nil, &scheduledFloodError{err}
+			}
+			if err != nil {
+				// fn may still be running if ctx is done.
+				return nil, err
+			}
+			return res, nil
+		})
+	}
+}
+
+// Log requests at debug level and failed requests at warn level.
+func LogSender(logger *slog.Logger) SenderMiddleware {
+	return func(next Sender) Sender {
+		return SenderFunc(func(ctx context.Context, chatID int64, c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+			start := time.Now()
+			res, err := next.Request(ctx, chatID, c)
+
+			attrs := []any{"request", fmt.Sprintf("%T", c), "chat_id", chatID, "duration", time.Since(start)}
+			if err != nil {
+				logger.WarnContext(ctx, "Bot API request failed.", append(attrs, "error_detail", err)...)
+			} else {
+				logger.DebugContext(ctx, "Bot API request.", attrs...)
+			}
+
+			return res, err
+		})
+	}
+}
+
+// Use s instead of the application's BotAPI for outbound requests. The retry
+// policy, the outbound scheduler and WithSenderMiddleware still apply.
+func WithSender(s Sender) OptionFunc {
+	return func(a *Application) {
+		a.Sender = s
+	}
+}
+
+// Decorate the application's sender with middlewares, inside the retry
+// policy and the outbound scheduler.
+func WithSenderMiddleware(middlewares ...SenderMiddleware) OptionFunc {
+	return func(a *Application) {
+		a.senderMiddlewares = append(a.senderMiddlewares, middlewares...)
+	}
+}
+
+// Return the sender of outbound requests, queuing them in scheduler if not
+// nil.
+func (a *Application) newSender(scheduler *OutboundScheduler) Sender {
+	s := a.Sender
+	if s == nil {
+		s = NewBotSender(a.BotAPI)
+	}
+
+	middlewares := []SenderMiddleware{RetrySender(a.RetryPolicy)}
+	if scheduler != nil {
+		middlewares = append(middlewares, RateLimitSender(scheduler))
+	}
+	middlewares = append(middlewares, a.senderMiddlewares...)
+
+	return ChainSender(s, middlewares...)
+}
+
+// Make request c with s and decode its result into v.
+func requestResult(ctx context.Context, s Sender, chatID int64, c tgbotapi.Chattable, v any) error {
+	res, err := s.Request(ctx, chatID, c)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(res.Result, v)
+}
+
+type extraParamsCtxKey struct{}
+
+// Return ctx adding extra to the parameters of the requests made with it.
+func withExtraParams(ctx context.Context, extra url.Values) context.Context {
+	if len(extra) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, extraParamsCtxKey{}, extra)
+}
+
+// ExtraParams returns the parameters to add to the requests made with ctx,
+// such as protect_content and message_thread_id, which tgbotapi cannot
+// express. The values must not be modified.
+func ExtraParams(ctx context.Context) url.Values {
+	extra, _ := ctx.Value(extraParamsCtxKey{}).(url.Values)
+	return extra
+}
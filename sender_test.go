@@ -0,0 +1,173 @@
+package tgbotapp_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	tgbotapp "github.com/nexoratech2025/go-telegram-bot-app"
+)
+
+type senderCall struct {
+	chatID int64
+	config tgbotapi.Chattable
+}
+
+// Sender recording requests and answering them with a message, or failing
+// with 429 for the first failures requests.
+type fakeSender struct {
+	calls    []senderCall
+	failures int
+}
+
+func (s *fakeSender) Request(ctx context.Context, chatID int64, c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	s.calls = append(s.calls, senderCall{chatID: chatID, config: c})
+
+	if s.failures > 0 {
+		s.failures--
+		return nil, &tgbotapi.Error{Code: 429, Message: "Too Many Requests"}
+	}
+
+	result, _ := json.Marshal(tgbotapi.Message{MessageID: 7, Chat: &tgbotapi.Chat{ID: chatID}})
+	return &tgbotapi.APIResponse{Ok: true, Result: result}, nil
+}
+
+func newFakeSenderHandlerContext(t *testing.T, sender tgbotapp.Sender, opts ...tgbotapp.OptionFunc) *tgbotapp.HandlerContext {
+	t.Helper()
+
+	opts = append([]tgbotapp.OptionFunc{func(a *tgbotapp.Application) {
+		a.Logger = slog.Default()
+	}, tgbotapp.WithSender(sender)}, opts...)
+
+	app := tgbotapp.New(nil, opts...)
+	return tgbotapp.NewHandlerContext(tgbotapp.NewBotContext(t.Context(), app, newChatUpdate(5, "")), "test")
+}
+
+func TestWithSenderShouldReceiveHandlerRequests(t *testing.T) {
+	// Arrange
+	sender := &fakeSender{}
+	h := newFakeSenderHandlerContext(t, sender)
+
+	// Act
+	msg, err := h.SendPhoto(tgbotapi.FileID("AgAC"), "caption")
+
+	// Assert
+	if err != nil || msg.MessageID != 7 {
+		t.Errorf("Expected decoded message 7, found %d, %v", msg.MessageID, err)
+	}
+	if len(sender.calls) != 1 || sender.calls[0].chatID != 5 {
+		t.Fatalf("Expected one request to chat 5, found %+v", sender.calls)
+	}
+	if _, ok := sender.calls[0].config.(tgbotapi.PhotoConfig); !ok {
+		t.Errorf("Expected photo request, found %T", sender.calls[0].config)
+	}
+}
+
+func TestSenderShouldApplyRetryPolicy(t *testing.T) {
+	// Arrange
+	sender := &fakeSender{failures: 1}
+	h := newFakeSenderHandlerContext(t, sender,
+		tgbotapp.WithRetryPolicy(tgbotapp.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}))
+
+	// Act
+	_, err := h.SendMessage("hello")
+
+	// Assert
+	if err != nil || len(sender.calls) != 2 {
+		t.Errorf("Expected request to be retried once, found %d calls, %v", len(sender.calls), err)
+	}
+}
+
+func TestWithSenderMiddlewareShouldDecorateInOrder(t *testing.T) {
+	// Arrange
+	var order []string
+	trace := func(name string) tgbotapp.SenderMiddleware {
+		return func(next tgbotapp.Sender) tgbotapp.Sender {
+			return tgbotapp.SenderFunc(func(ctx context.Context, chatID int64, c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+				order = append(order, name)
+				return next.Request(ctx, chatID, c)
+			})
+		}
+	}
+	h := newFakeSenderHandlerContext(t, &fakeSender{}, tgbotapp.WithSenderMiddleware(trace("outer"), trace("inner")))
+
+	// Act
+	h.SendMessage("hello")
+
+	// Assert
+	if strings.Join(order, ",") != "outer,inner" {
+		t.Errorf("Expected outer then inner, found %v", order)
+	}
+}
+
+func TestLogSenderShouldLogFailedRequests(t *testing.T) {
+	// Arrange
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	sender := tgbotapp.ChainSender(&fakeSender{failures: 1}, tgbotapp.LogSender(logger))
+
+	// Act
+	_, err := sender.Request(t.Context(), 5, tgbotapi.NewMessage(5, "hello"))
+
+	// Assert
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) {
+		t.Errorf("Expected API error, found %v", err)
+	}
+	if out := buf.String(); !strings.Contains(out, "Bot API request failed.") || !strings.Contains(out, "MessageConfig") {
+		t.Errorf("Expected failed request to be logged, found %q", out)
+	}
+}
+
+func TestRetrySenderShouldNotRetryFloodWaitsOfScheduler(t *testing.T) {
+	// Arrange
+	calls := 0
+	base := tgbotapp.SenderFunc(func(ctx context.Context, chatID int64, c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+		calls++
+		return nil, &tgbotapi.Error{Code: 429, Message: "Too Many Requests", ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 1}}
+	})
+
+	scheduler := tgbotapp.NewOutboundScheduler(tgbotapp.RateLimits{MaxRetryAfter: 0})
+	defer scheduler.Close()
+
+	sender := tgbotapp.ChainSender(base,
+		tgbotapp.RetrySender(tgbotapp.RetryPolicy{MaxAttempts: 3}),
+		tgbotapp.RateLimitSender(scheduler),
+	)
+
+	// Act
+	_, err := sender.Request(t.Context(), 5, tgbotapi.NewMessage(5, "hello"))
+
+	// Assert
+	if tgbotapp.RetryAfter(err) != time.Second {
+		t.Errorf("Expected flood wait error, found %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected %d call, found %d", 1, calls)
+	}
+}
+
+func TestExtraParamsShouldBeAvailableToCustomSenders(t *testing.T) {
+	// Arrange
+	var params url.Values
+	sender := tgbotapp.SenderFunc(func(ctx context.Context, chatID int64, c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+		params = tgbotapp.ExtraParams(ctx)
+		return &tgbotapi.APIResponse{Ok: true, Result: []byte(`{"message_id":1}`)}, nil
+	})
+	h := newFakeSenderHandlerContext(t, sender)
+
+	// Act
+	_, err := h.SendText("hello", tgbotapp.Protect(), tgbotapp.ThreadID(3))
+
+	// Assert
+	if err != nil || params.Get("protect_content") != "true" || params.Get("message_thread_id") != "3" {
+		t.Errorf("Expected extra parameters, found %v, %v", params, err)
+	}
+}
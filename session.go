@@ -1,9 +1,12 @@
 package tgbotapp
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"sync"
+	"time"
 
 	"github.com/nexoratech2025/go-telegram-bot-app/session"
 )
@@ -16,71 +19,322 @@ var (
 	ErrEmptySessionManager = errors.New("Session Manager is nil.")
 )
 
-func SessionMiddleware(manager session.SessionManager[int64]) Middleware {
+// Control the session middleware behaviour.
+type SessionOption func(*sessionOptions)
+
+// What the session middleware does when a session cannot be loaded.
+type LoadErrorPolicy int
+
+const (
+	// Log a warning and run the handler with an empty session that is never
+	// saved. This is the default.
+	LoadErrorEphemeral LoadErrorPolicy = iota
+	// Skip the handler and report the error.
+	LoadErrorFail
+	// Retry loading, then fail the update if all attempts failed.
+	LoadErrorRetry
+)
+
+type sessionOptions struct {
+	conflictAttempts int
+	conflictBackoff  time.Duration
+	onSaveError      func(ctx *BotContext, err error)
+	loadPolicy       LoadErrorPolicy
+	loadAttempts     int
+	loadBackoff      time.Duration
+	onLoadError      func(ctx *BotContext, err error)
+}
+
+// Retry saving the session up to attempts times when it fails with
+// session.ErrVersionConflict. The handler runs once: its changes are applied
+// again to a freshly loaded session, so keys written concurrently by other
+// updates are kept and keys written by both end up with the handler's value.
+func WithConflictRetry(attempts int, backoff time.Duration) SessionOption {
+	return func(o *sessionOptions) {
+		o.conflictAttempts = attempts
+		o.conflictBackoff = backoff
+	}
+}
+
+// Called when saving or deleting the session fails after the handler returned.
+// By default the error is logged.
+func WithSaveErrorHandler(f func(ctx *BotContext, err error)) SessionOption {
+	return func(o *sessionOptions) {
+		o.onSaveError = f
+	}
+}
+
+func WithLoadErrorPolicy(policy LoadErrorPolicy) SessionOption {
+	return func(o *sessionOptions) {
+		o.loadPolicy = policy
+	}
+}
+
+// Retry loading the session up to attempts times before failing the update.
+func WithLoadRetry(attempts int, backoff time.Duration) SessionOption {
+	return func(o *sessionOptions) {
+		o.loadPolicy = LoadErrorRetry
+		o.loadAttempts = attempts
+		o.loadBackoff = backoff
+	}
+}
+
+// Called when the update fails because its session cannot be loaded.
+// By default the error is logged.
+func WithLoadErrorHandler(f func(ctx *BotContext, err error)) SessionOption {
+	return func(o *sessionOptions) {
+		o.onLoadError = f
+	}
+}
+
+func defaultSaveErrorHandler(ctx *BotContext, err error) {
+	ctx.Logger().ErrorContext(ctx.Ctx, "Failed to save session", "error", err)
+}
+
+func defaultLoadErrorHandler(ctx *BotContext, err error) {
+	ctx.Logger().ErrorContext(ctx.Ctx, "Failed to load session, update dropped", "error", err)
+}
+
+// Loads the session of the update's chat before calling next and writes it
+// back afterwards. Sessions are only saved if they were modified, and deleted
+// if the handler destroyed them. Managers implementing
+// session.ContextSessionManager receive the update's context.
+func SessionMiddleware(manager session.SessionManager[int64], opts ...SessionOption) Middleware {
+	if manager == nil {
+		return ContextSessionMiddleware(nil, opts...)
+	}
+
+	return newSessionMiddleware(session.WithContext(manager), manager, opts)
+}
+
+// Same as SessionMiddleware for managers that only implement session.ContextSessionManager.
+func ContextSessionMiddleware(manager session.ContextSessionManager[int64], opts ...SessionOption) Middleware {
+	return newSessionMiddleware(manager, manager, opts)
+}
+
+func newSessionMiddleware(manager session.ContextSessionManager[int64], impl any, opts []SessionOption) Middleware {
+
+	options := sessionOptions{
+		conflictAttempts: 1,
+		onSaveError:      defaultSaveErrorHandler,
+		loadAttempts:     1,
+		onLoadError:      defaultLoadErrorHandler,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	locker, _ := impl.(session.Locker[int64])
+	cas, _ := impl.(session.CompareAndSetter[int64])
 
 	return func(ctx *BotContext, next HandlerFunc) {
 
 		if manager == nil {
 			ctx.Logger().ErrorContext(ctx.Ctx, "No session manager available.")
 			next(ctx)
-		} else {
+			return
+		}
 
-			chat := ctx.Update.FromChat()
+		chat := ctx.Update.FromChat()
 
-			if chat != nil {
-				chatID := chat.ChatConfig().ChatID
-				session, err := manager.GetOrCreate(chatID)
-				if err != nil {
-					ctx.Logger().WarnContext(ctx.Ctx, "Failed to retrieve session", "error", err)
-				}
+		if chat == nil {
+			ctx.Logger().WarnContext(ctx.Ctx, "Cannot retrieve chatID from chat update", "update_id", ctx.Update.UpdateID)
+			next(ctx)
+			return
+		}
+
+		chatID := chat.ChatConfig().ChatID
 
-				ctx.Session = session
-				next(ctx)
-				manager.Set(chatID, ctx.Session)
+		if locker != nil {
+			unlock, err := locker.Lock(ctx.Ctx, chatID)
+			if err != nil {
+				ctx.Logger().ErrorContext(ctx.Ctx, "Failed to lock session", "chat_id", chatID, "error", err)
+				return
+			}
+			defer unlock()
+		}
 
-			} else {
-				ctx.Logger().WarnContext(ctx.Ctx, "Cannot retrieve chatID from chat update", "update_id", ctx.Update.UpdateID)
-				next(ctx)
+		sess, err := loadSession(ctx, manager, chatID, &options)
+		ephemeral := false
+		if err != nil {
+			if options.loadPolicy != LoadErrorEphemeral {
+				options.onLoadError(ctx, fmt.Errorf("chat %d: %w", chatID, err))
+				return
 			}
+			ctx.Logger().WarnContext(ctx.Ctx, "Failed to load session, using ephemeral session", "chat_id", chatID, "error", err)
+			sess = NewDefaultSession()
+			ephemeral = true
+		}
+
+		var base session.Snapshot
+		if options.conflictAttempts > 1 && !ephemeral {
+			base = session.TakeSnapshot(sess)
+		}
+
+		ctx.Session = sess
+		next(ctx)
+
+		if ctx.Session == nil || ephemeral {
+			return
 		}
 
+		err = saveSession(ctx.Ctx, manager, cas, chatID, ctx.Session)
+		for attempt := 1; errors.Is(err, session.ErrVersionConflict) && attempt < options.conflictAttempts; attempt++ {
+			ctx.Logger().WarnContext(ctx.Ctx, "Session conflict, retrying save", "chat_id", chatID, "attempt", attempt)
+			if !sleepContext(ctx.Ctx, options.conflictBackoff) {
+				break
+			}
+			err = rebaseSession(ctx, manager, cas, chatID, base)
+		}
+
+		if err != nil {
+			options.onSaveError(ctx, fmt.Errorf("chat %d: %w", chatID, err))
+		}
+
+	}
+
+}
+
+// Apply the changes the handler made since base to a freshly loaded session
+// and save it. The session replaces ctx.Session.
+func rebaseSession(ctx *BotContext, manager session.ContextSessionManager[int64], cas session.CompareAndSetter[int64], chatID int64, base session.Snapshot) error {
+	fresh, err := manager.GetOrCreateContext(ctx.Ctx, chatID)
+	if err != nil {
+		return err
+	}
+
+	// The handler already fired the hooks of its changes.
+	changed := session.TakeSnapshot(ctx.Session)
+	session.Silently(fresh, func() { session.Rebase(fresh, base, changed) })
+	ctx.Session = fresh
+
+	return saveSession(ctx.Ctx, manager, cas, chatID, fresh)
+}
+
+func loadSession(ctx *BotContext, manager session.ContextSessionManager[int64], chatID int64, options *sessionOptions) (session.Sessioner, error) {
+	attempts := 1
+	if options.loadPolicy == LoadErrorRetry {
+		attempts = max(options.loadAttempts, 1)
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		var sess session.Sessioner
+		sess, err = manager.GetOrCreateContext(ctx.Ctx, chatID)
+		if err == nil && sess == nil {
+			err = fmt.Errorf(ErrSessionNotFound, chatID)
+		}
+		if err == nil {
+			return sess, nil
+		}
+
+		if attempt < attempts {
+			ctx.Logger().WarnContext(ctx.Ctx, "Failed to load session, retrying", "chat_id", chatID, "attempt", attempt, "error", err)
+			if !sleepContext(ctx.Ctx, options.loadBackoff) {
+				return nil, errors.Join(err, ctx.Ctx.Err())
+			}
+		}
+	}
+
+	return nil, err
+}
+
+func saveSession(ctx context.Context, manager session.ContextSessionManager[int64], cas session.CompareAndSetter[int64], chatID int64, sess session.Sessioner) error {
+	if d, ok := sess.(session.Destroyer); ok && d.IsDestroyed() {
+		return manager.DeleteContext(ctx, chatID)
+	}
+
+	if !session.IsDirty(sess) {
+		return nil
+	}
+
+	if cas != nil {
+		if _, ok := sess.(session.Versioned); ok {
+			return cas.CompareAndSet(ctx, chatID, sess)
+		}
+	}
+	return manager.SetContext(ctx, chatID, sess)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
 	}
 
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
 }
 
+// Default session implementation. It is safe for concurrent use and tracks
+// modifications made since it was loaded.
 type DefaultSession struct {
-	data  map[string]any
-	state string
+	mu        sync.RWMutex
+	data      map[string]any
+	stack     []session.Frame
+	version   uint64
+	dirty     bool
+	destroyed bool
+	// Created by a manager and not stored yet.
+	unsaved bool
+	// Listeners of a manager are attached.
+	observed bool
+	muted    int
+
+	onStateChange []func(oldState, newState string)
+	onClear       []func()
 }
 
 func NewDefaultSession() session.Sessioner {
 	return &DefaultSession{
-		data: make(map[string]any),
+		data:  make(map[string]any),
+		stack: []session.Frame{{}},
 	}
 }
 
 func (s *DefaultSession) CurrentState() string {
-	return s.state
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.top().State
 }
 
+// SetState replaces the state of the top frame of the state stack.
 func (s *DefaultSession) SetState(state string) {
-	s.state = state
+	s.Replace(state)
 }
 
 func (s *DefaultSession) Get(key string) (value any, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	value, ok = s.data[key]
 	return
 }
 
 func (s *DefaultSession) Set(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.data[key] = value
+	s.dirty = true
 }
 
 func (s *DefaultSession) Delete(key string) {
-	delete(s.data, key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data[key]; ok {
+		delete(s.data, key)
+		s.dirty = true
+	}
 }
 
 func (s *DefaultSession) GetAllKeys() (keys []string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
 	for k := range s.data {
 		keys = append(keys, k)
@@ -90,20 +344,143 @@ func (s *DefaultSession) GetAllKeys() (keys []string) {
 }
 
 func (s *DefaultSession) ClearData() {
-	for _, key := range s.GetAllKeys() {
-		s.Delete(key)
+	s.mu.Lock()
+	if len(s.data) == 0 {
+		s.mu.Unlock()
+		return
+	}
+	clear(s.data)
+	s.dirty = true
+	listeners := listenersOf(s, s.onClear)
+	s.mu.Unlock()
+
+	for _, f := range listeners {
+		f()
+	}
+}
+
+// OnStateChange implements session.Observable.
+func (s *DefaultSession) OnStateChange(f func(oldState, newState string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onStateChange = append(s.onStateChange, f)
+}
+
+// OnClear implements session.Observable.
+func (s *DefaultSession) OnClear(f func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onClear = append(s.onClear, f)
+}
+
+// Mute implements session.Muter.
+func (s *DefaultSession) Mute() func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.muted++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.muted--
+		})
+	}
+}
+
+// Return l, or nothing while the session is muted. Requires s.mu.
+func listenersOf[T any](s *DefaultSession, l []T) []T {
+	if s.muted > 0 {
+		return nil
 	}
+	return l
+}
+
+// Report whether the session was created by a manager and not stored yet,
+// and forget it.
+func (s *DefaultSession) takeUnsaved() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unsaved := s.unsaved
+	s.unsaved = false
+	return unsaved
+}
+
+// Report whether the session was not observed yet, and mark it observed.
+func (s *DefaultSession) markObserved() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	first := !s.observed
+	s.observed = true
+	return first
+}
+
+// IsDirty implements session.DirtyTracker.
+func (s *DefaultSession) IsDirty() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.dirty
+}
+
+// MarkClean implements session.DirtyTracker.
+func (s *DefaultSession) MarkClean() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dirty = false
+}
+
+// Destroy clears the session and marks it for deletion by the session middleware.
+func (s *DefaultSession) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.data)
+	s.stack = []session.Frame{{}}
+	s.destroyed = true
+}
+
+// IsDestroyed implements session.Destroyer.
+func (s *DefaultSession) IsDestroyed() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.destroyed
+}
+
+// Version implements session.Versioned.
+func (s *DefaultSession) Version() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.version
+}
+
+// SetVersion implements session.Versioned.
+func (s *DefaultSession) SetVersion(version uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version = version
+}
+
+// Attach the hooks returned by get to sess, unless sess is a DefaultSession
+// that already has listeners of a manager.
+func observeSession(sess session.Sessioner, chatID int64, get func() session.Hooks[int64]) {
+	if d, ok := sess.(*DefaultSession); ok && !d.markObserved() {
+		return
+	}
+	session.Observe(sess, chatID, get)
 }
 
 // Default Implementation for Session In Memory Manager.
 type DefaultInMemoryManager struct {
 	registry map[int64]session.Sessioner
 	mu       sync.RWMutex
+	locks    *keyedLocker
+	hooks    session.Hooks[int64]
 }
 
 func NewDefaultInMemoryManager() session.SessionManager[int64] {
 	return &DefaultInMemoryManager{
 		registry: make(map[int64]session.Sessioner),
+		locks:    newKeyedLocker(),
 	}
 }
 
@@ -111,43 +488,163 @@ func (s *DefaultInMemoryManager) GetOrCreate(chatID int64) (session.Sessioner, e
 	s.mu.RLock()
 	sess, ok := s.registry[chatID]
 	s.mu.RUnlock()
+	if ok {
+		return sess, nil
+	}
+
+	s.mu.Lock()
+	sess, ok = s.registry[chatID]
 	if !ok {
-		s.mu.Lock()
-		defer s.mu.Unlock()
 		sess = NewDefaultSession()
+		observeSession(sess, chatID, s.getHooks)
 		s.registry[chatID] = sess
 	}
+	h := s.hooks
+	s.mu.Unlock()
+
+	if !ok && h.OnCreate != nil {
+		h.OnCreate(chatID, sess)
+	}
 
 	return sess, nil
 
 }
 
-func (s *DefaultInMemoryManager) Set(chatID int64, session session.Sessioner) error {
+func (s *DefaultInMemoryManager) Set(chatID int64, sess session.Sessioner) error {
+	if sess == nil {
+		return NewErrInvalidArgument("session must not be nil.", "session")
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	sess, ok := s.registry[chatID]
-	if !ok {
-		return fmt.Errorf(ErrSessionNotFound, chatID)
+	if s.registry[chatID] != sess {
+		observeSession(sess, chatID, s.getHooks)
 	}
-
 	s.registry[chatID] = sess
+	session.MarkClean(sess)
 
 	return nil
 }
 
 func (s *DefaultInMemoryManager) Delete(chatID int64) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	_, ok := s.registry[chatID]
 	delete(s.registry, chatID)
+	h := s.hooks
+	s.mu.Unlock()
+
+	if ok && h.OnDelete != nil {
+		h.OnDelete(chatID)
+	}
+	return nil
+}
+
+// Range implements session.Enumerable.
+func (s *DefaultInMemoryManager) Range(ctx context.Context, f func(chatID int64, sess session.Sessioner) bool) error {
+	s.mu.RLock()
+	registry := maps.Clone(s.registry)
+	s.mu.RUnlock()
+
+	for chatID, sess := range registry {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !f(chatID, sess) {
+			break
+		}
+	}
+
 	return nil
 }
 
+// Find implements session.Finder.
+func (s *DefaultInMemoryManager) Find(ctx context.Context, chatID int64) (session.Sessioner, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sess, ok := s.registry[chatID]
+	return sess, ok, nil
+}
+
+// SetHooks implements session.Hookable.
+func (s *DefaultInMemoryManager) SetHooks(hooks session.Hooks[int64]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = hooks
+}
+
+func (s *DefaultInMemoryManager) getHooks() session.Hooks[int64] {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.hooks
+}
+
+// Lock implements session.Locker. Updates for the same chat are processed
+// one at a time while the lock is held.
+func (s *DefaultInMemoryManager) Lock(ctx context.Context, chatID int64) (func(), error) {
+	return s.locks.Lock(ctx, chatID)
+}
+
 func (s *DefaultSession) ClearState() {
-	s.state = ""
+	s.SetState("")
 }
 
 func (s *DefaultSession) ClearAll() {
 	s.ClearData()
 	s.ClearState()
 }
+
+// Per key mutex whose entries are dropped once nobody holds or waits for them.
+type keyedLocker struct {
+	mu    sync.Mutex
+	locks map[int64]*keyedLock
+}
+
+type keyedLock struct {
+	ch   chan struct{}
+	refs int
+}
+
+func newKeyedLocker() *keyedLocker {
+	return &keyedLocker{
+		locks: make(map[int64]*keyedLock),
+	}
+}
+
+func (k *keyedLocker) Lock(ctx context.Context, key int64) (func(), error) {
+	k.mu.Lock()
+	l, ok := k.locks[key]
+	if !ok {
+		l = &keyedLock{ch: make(chan struct{}, 1)}
+		k.locks[key] = l
+	}
+	l.refs++
+	k.mu.Unlock()
+
+	select {
+	case l.ch <- struct{}{}:
+		var once sync.Once
+		return func() {
+			once.Do(func() {
+				<-l.ch
+				k.release(key, l)
+			})
+		}, nil
+	case <-ctx.Done():
+		k.release(key, l)
+		return nil, ctx.Err()
+	}
+}
+
+func (k *keyedLocker) release(key int64, l *keyedLock) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	l.refs--
+	if l.refs == 0 {
+		delete(k.locks, key)
+	}
+}
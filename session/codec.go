@@ -0,0 +1,177 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Codec converts a session value to and from its stored representation.
+type Codec interface {
+	Encode(value any) ([]byte, error)
+	Decode(data []byte) (any, error)
+}
+
+// JSONCodec encodes values of type T as JSON and decodes them back into T.
+type JSONCodec[T any] struct{}
+
+func (JSONCodec[T]) Encode(value any) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (JSONCodec[T]) Decode(data []byte) (any, error) {
+	var value T
+	err := json.Unmarshal(data, &value)
+	return value, err
+}
+
+// EncodedValue is the stored form of a single session value. Type is the name
+// the value's type was registered with, or empty for unregistered types.
+type EncodedValue struct {
+	Type  string          `json:"type,omitempty"`
+	Value json.RawMessage `json:"value"`
+}
+
+type codecEntry struct {
+	name  string
+	codec Codec
+}
+
+// CodecRegistry maps Go types to named codecs so that session values keep
+// their type when they go through a persistent store.
+type CodecRegistry struct {
+	mu     sync.RWMutex
+	byName map[string]codecEntry
+	byType map[reflect.Type]codecEntry
+}
+
+// DefaultCodecs is used by stores that are not given a registry explicitly.
+var DefaultCodecs = NewCodecRegistry()
+
+// Return new registry with codecs for the basic types registered.
+func NewCodecRegistry() *CodecRegistry {
+	r := &CodecRegistry{
+		byName: make(map[string]codecEntry),
+		byType: make(map[reflect.Type]codecEntry),
+	}
+
+	mustRegister[string](r, "string")
+	mustRegister[bool](r, "bool")
+	mustRegister[int](r, "int")
+	mustRegister[int32](r, "int32")
+	mustRegister[int64](r, "int64")
+	mustRegister[uint](r, "uint")
+	mustRegister[uint64](r, "uint64")
+	mustRegister[float32](r, "float32")
+	mustRegister[float64](r, "float64")
+	mustRegister[[]string](r, "[]string")
+	mustRegister[[]int](r, "[]int")
+	mustRegister[[]int64](r, "[]int64")
+	mustRegister[map[string]string](r, "map[string]string")
+
+	return r
+}
+
+func mustRegister[T any](r *CodecRegistry, name string) {
+	if err := RegisterCodec[T](r, name, JSONCodec[T]{}); err != nil {
+		panic(err)
+	}
+}
+
+// RegisterCodec registers codec for values of type T under name.
+func RegisterCodec[T any](r *CodecRegistry, name string, codec Codec) error {
+	if name == "" {
+		return fmt.Errorf("session: codec name must not be empty")
+	}
+
+	t := reflect.TypeFor[T]()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if e, ok := r.byName[name]; ok {
+		return fmt.Errorf("session: codec name %q already registered for %s", name, e.name)
+	}
+	if e, ok := r.byType[t]; ok {
+		return fmt.Errorf("session: type %s already registered as %q", t, e.name)
+	}
+
+	e := codecEntry{name: name, codec: codec}
+	r.byName[name] = e
+	r.byType[t] = e
+
+	return nil
+}
+
+// RegisterType registers T with a JSON codec in DefaultCodecs.
+func RegisterType[T any](name string) error {
+	return RegisterCodec[T](DefaultCodecs, name, JSONCodec[T]{})
+}
+
+// EncodeValue encodes value with the codec registered for its type. Values of
+// unregistered types are stored as plain JSON.
+func (r *CodecRegistry) EncodeValue(value any) (EncodedValue, error) {
+	r.mu.RLock()
+	e, ok := r.byType[reflect.TypeOf(value)]
+	r.mu.RUnlock()
+
+	if !ok {
+		b, err := json.Marshal(value)
+		return EncodedValue{Value: b}, err
+	}
+
+	b, err := e.codec.Encode(value)
+	if err != nil {
+		return EncodedValue{}, fmt.Errorf("session: encode %q: %w", e.name, err)
+	}
+
+	return EncodedValue{Type: e.name, Value: b}, nil
+}
+
+// DecodeValue reverses EncodeValue. Values without a type, or with a type name
+// that is not registered, are decoded as plain JSON.
+func (r *CodecRegistry) DecodeValue(v EncodedValue) (any, error) {
+	r.mu.RLock()
+	e, ok := r.byName[v.Type]
+	r.mu.RUnlock()
+
+	if !ok {
+		var value any
+		err := json.Unmarshal(v.Value, &value)
+		return value, err
+	}
+
+	value, err := e.codec.Decode(v.Value)
+	if err != nil {
+		return nil, fmt.Errorf("session: decode %q: %w", e.name, err)
+	}
+
+	return value, nil
+}
+
+// EncodeData encodes every value of data.
+func (r *CodecRegistry) EncodeData(data map[string]any) (map[string]EncodedValue, error) {
+	out := make(map[string]EncodedValue, len(data))
+	for k, v := range data {
+		ev, err := r.EncodeValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("session: key %q: %w", k, err)
+		}
+		out[k] = ev
+	}
+	return out, nil
+}
+
+// DecodeData decodes every value of data.
+func (r *CodecRegistry) DecodeData(data map[string]EncodedValue) (map[string]any, error) {
+	out := make(map[string]any, len(data))
+	for k, ev := range data {
+		v, err := r.DecodeValue(ev)
+		if err != nil {
+			return nil, fmt.Errorf("session: key %q: %w", k, err)
+		}
+		out[k] = v
+	}
+	return out, nil
+}
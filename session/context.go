@@ -0,0 +1,53 @@
+package session
+
+import (
+	"context"
+)
+
+// ContextSessionManager is a SessionManager whose operations honor
+// cancellation and deadlines of the given context.
+type ContextSessionManager[K comparable] interface {
+	GetOrCreateContext(ctx context.Context, id K) (Sessioner, error)
+	SetContext(ctx context.Context, id K, session Sessioner) error
+	DeleteContext(ctx context.Context, id K) error
+}
+
+// WithContext returns m as a ContextSessionManager. Managers that already
+// implement it are returned as is; others are wrapped in an adapter that
+// checks the context before delegating to m.
+func WithContext[K comparable](m SessionManager[K]) ContextSessionManager[K] {
+	if cm, ok := m.(ContextSessionManager[K]); ok {
+		return cm
+	}
+	return &contextAdapter[K]{m: m}
+}
+
+type contextAdapter[K comparable] struct {
+	m SessionManager[K]
+}
+
+func (a *contextAdapter[K]) GetOrCreateContext(ctx context.Context, id K) (Sessioner, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return a.m.GetOrCreate(id)
+}
+
+func (a *contextAdapter[K]) SetContext(ctx context.Context, id K, session Sessioner) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.m.Set(id, session)
+}
+
+func (a *contextAdapter[K]) DeleteContext(ctx context.Context, id K) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.m.Delete(id)
+}
+
+// Unwrap returns the adapted manager.
+func (a *contextAdapter[K]) Unwrap() SessionManager[K] {
+	return a.m
+}
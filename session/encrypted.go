@@ -0,0 +1,284 @@
+package session
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnknownKey      = errors.New("Session encryption key not found.")
+	ErrMalformedRecord = errors.New("Encrypted session record is malformed.")
+)
+
+// encryptedFormat is the first byte of every record written by an encrypted store.
+const encryptedFormat byte = 1
+
+// Keyring holds the AES keys of an encrypted store by key id. New records are
+// sealed with the primary key; records sealed with any other key in the ring
+// can still be opened, which allows keys to be rotated.
+type Keyring struct {
+	primary string
+	aeads   map[string]cipher.AEAD
+}
+
+// Return new keyring. Keys must be 16, 24 or 32 bytes long, and primary must
+// be one of the key ids.
+func NewKeyring(primary string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[primary]; !ok {
+		return nil, fmt.Errorf("session: primary key %q not in keyring", primary)
+	}
+
+	k := &Keyring{
+		primary: primary,
+		aeads:   make(map[string]cipher.AEAD, len(keys)),
+	}
+
+	for id, key := range keys {
+		if id == "" || len(id) > 255 {
+			return nil, fmt.Errorf("session: key id %q must be 1 to 255 bytes long", id)
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("session: key %q: %w", id, err)
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("session: key %q: %w", id, err)
+		}
+
+		k.aeads[id] = aead
+	}
+
+	return k, nil
+}
+
+// Seal encrypts plaintext with the primary key. additionalData is
+// authenticated but not encrypted, and must be passed to Open unchanged.
+//
+// Record layout: format | len(key id) | key id | nonce | ciphertext.
+func (k *Keyring) Seal(plaintext, additionalData []byte) ([]byte, error) {
+	aead := k.aeads[k.primary]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, 2+len(k.primary)+len(nonce)+len(plaintext)+aead.Overhead())
+	out = append(out, encryptedFormat, byte(len(k.primary)))
+	out = append(out, k.primary...)
+	out = append(out, nonce...)
+
+	return aead.Seal(out, nonce, plaintext, additionalData), nil
+}
+
+// Open decrypts a record produced by Seal with any key of the ring.
+func (k *Keyring) Open(record, additionalData []byte) ([]byte, error) {
+	keyID, aead, rest, err := k.parse(record)
+	if err != nil {
+		return nil, err
+	}
+
+	n := aead.NonceSize()
+	if len(rest) < n {
+		return nil, ErrMalformedRecord
+	}
+
+	plaintext, err := aead.Open(nil, rest[:n], rest[n:], additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("session: open record sealed with key %q: %w", keyID, err)
+	}
+
+	return plaintext, nil
+}
+
+// KeyID returns the id of the key that sealed record.
+func (k *Keyring) KeyID(record []byte) (string, error) {
+	id, _, _, err := k.parse(record)
+	return id, err
+}
+
+func (k *Keyring) parse(record []byte) (keyID string, aead cipher.AEAD, rest []byte, err error) {
+	if len(record) < 2 || record[0] != encryptedFormat {
+		return "", nil, nil, ErrMalformedRecord
+	}
+
+	n := int(record[1])
+	if len(record) < 2+n {
+		return "", nil, nil, ErrMalformedRecord
+	}
+
+	keyID = string(record[2 : 2+n])
+	aead, ok := k.aeads[keyID]
+	if !ok {
+		return keyID, nil, nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+
+	return keyID, aead, record[2+n:], nil
+}
+
+// Rotator is implemented by stores returned from NewEncryptedStore.
+type Rotator interface {
+	Rotate(ctx context.Context) (int, error)
+}
+
+// NewEncryptedStore wraps store so that it only ever sees sealed records.
+// The session id is bound to each record as additional data, so records
+// cannot be swapped between sessions. If store is a VersionedStore, so is the
+// returned store. The returned store implements Rotator.
+func NewEncryptedStore[K comparable](store Store[K], keys *Keyring) Store[K] {
+	e := &EncryptedStore[K]{store: store, keys: keys}
+
+	if vs, ok := store.(VersionedStore[K]); ok {
+		return &encryptedVersionedStore[K]{EncryptedStore: e, versioned: vs}
+	}
+
+	return e
+}
+
+// EncryptedStore seals session records with AES-GCM before they reach the
+// underlying store.
+type EncryptedStore[K comparable] struct {
+	store Store[K]
+	keys  *Keyring
+}
+
+func (s *EncryptedStore[K]) Load(ctx context.Context, id K) ([]byte, error) {
+	record, err := s.store.Load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.keys.Open(record, additionalData(id))
+}
+
+func (s *EncryptedStore[K]) Save(ctx context.Context, id K, data []byte) error {
+	record, err := s.keys.Seal(data, additionalData(id))
+	if err != nil {
+		return err
+	}
+	return s.store.Save(ctx, id, record)
+}
+
+func (s *EncryptedStore[K]) Delete(ctx context.Context, id K) error {
+	return s.store.Delete(ctx, id)
+}
+
+// Keys implements KeyLister if the underlying store does.
+func (s *EncryptedStore[K]) Keys(ctx context.Context) ([]K, error) {
+	lister, ok := s.store.(KeyLister[K])
+	if !ok {
+		return nil, ErrNotEnumerable
+	}
+	return lister.Keys(ctx)
+}
+
+// Rotate re-seals every record that was not sealed with the primary key and
+// returns the number of rewritten records. The underlying store must
+// implement KeyLister. If it is a VersionedStore, records are rewritten with
+// CompareAndSave so that concurrent saves are never overwritten.
+func (s *EncryptedStore[K]) Rotate(ctx context.Context) (int, error) {
+	ids, err := s.Keys(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, id := range ids {
+		rotated, err := s.rotate(ctx, id)
+		if err != nil {
+			return n, fmt.Errorf("session %v: %w", id, err)
+		}
+		if rotated {
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+// Number of times a record is loaded again after a version conflict.
+const rotateAttempts = 3
+
+// Re-seal the record of id with the primary key. Returns false if it did not
+// need to be rewritten.
+func (s *EncryptedStore[K]) rotate(ctx context.Context, id K) (bool, error) {
+	vs, versioned := s.store.(VersionedStore[K])
+
+	for range rotateAttempts {
+		var record []byte
+		var version uint64
+		var err error
+		if versioned {
+			record, version, err = vs.LoadVersion(ctx, id)
+		} else {
+			record, err = s.store.Load(ctx, id)
+		}
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		if keyID, err := s.keys.KeyID(record); err == nil && keyID == s.keys.primary {
+			return false, nil
+		}
+
+		data, err := s.keys.Open(record, additionalData(id))
+		if err != nil {
+			return false, err
+		}
+
+		if !versioned {
+			return true, s.Save(ctx, id, data)
+		}
+
+		record, err = s.keys.Seal(data, additionalData(id))
+		if err != nil {
+			return false, err
+		}
+
+		_, err = vs.CompareAndSave(ctx, id, record, version)
+		if errors.Is(err, ErrVersionConflict) {
+			// Saved meanwhile, most likely with the primary key already.
+			continue
+		}
+		return err == nil, err
+	}
+
+	// Still changing, the next rotation picks it up if needed.
+	return false, nil
+}
+
+type encryptedVersionedStore[K comparable] struct {
+	*EncryptedStore[K]
+	versioned VersionedStore[K]
+}
+
+func (s *encryptedVersionedStore[K]) LoadVersion(ctx context.Context, id K) ([]byte, uint64, error) {
+	record, version, err := s.versioned.LoadVersion(ctx, id)
+	if err != nil {
+		return nil, version, err
+	}
+
+	data, err := s.keys.Open(record, additionalData(id))
+	return data, version, err
+}
+
+func (s *encryptedVersionedStore[K]) CompareAndSave(ctx context.Context, id K, data []byte, version uint64) (uint64, error) {
+	record, err := s.keys.Seal(data, additionalData(id))
+	if err != nil {
+		return 0, err
+	}
+	return s.versioned.CompareAndSave(ctx, id, record, version)
+}
+
+func additionalData[K comparable](id K) []byte {
+	return fmt.Appendf(nil, "session:%v", id)
+}
@@ -0,0 +1,138 @@
+package session_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	tgbotapp "github.com/nexoratech2025/go-telegram-bot-app"
+	"github.com/nexoratech2025/go-telegram-bot-app/session"
+)
+
+var (
+	oldKey = bytes.Repeat([]byte{1}, 32)
+	newKey = bytes.Repeat([]byte{2}, 32)
+)
+
+func mustKeyring(t *testing.T, primary string, keys map[string][]byte) *session.Keyring {
+	t.Helper()
+	k, err := session.NewKeyring(primary, keys)
+	if err != nil {
+		t.Fatalf("Should not return error. Got error: %v", err)
+	}
+	return k
+}
+
+func TestEncryptedStoreShouldNotLeakPlaintext(t *testing.T) {
+	backend := tgbotapp.NewMemoryStore()
+	store := session.NewEncryptedStore(backend, mustKeyring(t, "k1", map[string][]byte{"k1": oldKey}))
+	mgr := tgbotapp.NewStoreManager(store, nil)
+
+	s, _ := mgr.GetOrCreate(1)
+	s.Set("phone", "+95912345678")
+	if err := mgr.Set(1, s); err != nil {
+		t.Fatalf("Should not return error. Got error: %v", err)
+	}
+
+	raw, _ := backend.Load(t.Context(), 1)
+	if bytes.Contains(raw, []byte("+95912345678")) {
+		t.Errorf("Expected stored record to be encrypted, found plaintext")
+	}
+
+	s, err := mgr.GetOrCreate(1)
+	if err != nil {
+		t.Fatalf("Should not return error. Got error: %v", err)
+	}
+	if v, _ := session.Get[string](s, "phone"); v != "+95912345678" {
+		t.Errorf("Expected decrypted phone, found %q", v)
+	}
+}
+
+func TestEncryptedStoreShouldKeepVersioning(t *testing.T) {
+	store := session.NewEncryptedStore(tgbotapp.NewMemoryStore(), mustKeyring(t, "k1", map[string][]byte{"k1": oldKey}))
+
+	if _, ok := store.(session.VersionedStore[int64]); !ok {
+		t.Errorf("Expected encrypted store over a versioned store to be versioned")
+	}
+}
+
+func TestEncryptedStoreShouldRejectSwappedRecords(t *testing.T) {
+	backend := tgbotapp.NewMemoryStore()
+	store := session.NewEncryptedStore(backend, mustKeyring(t, "k1", map[string][]byte{"k1": oldKey}))
+
+	store.Save(t.Context(), 1, []byte("one"))
+	raw, _ := backend.Load(t.Context(), 1)
+	backend.Save(t.Context(), 2, raw)
+
+	if _, err := store.Load(t.Context(), 2); err == nil {
+		t.Errorf("Should return error. got no error")
+	}
+}
+
+func TestEncryptedStoreShouldRotateKeys(t *testing.T) {
+	backend := tgbotapp.NewMemoryStore()
+	old := session.NewEncryptedStore(backend, mustKeyring(t, "k1", map[string][]byte{"k1": oldKey}))
+	old.Save(t.Context(), 1, []byte("one"))
+	old.Save(t.Context(), 2, []byte("two"))
+
+	rotating := session.NewEncryptedStore(backend, mustKeyring(t, "k2", map[string][]byte{"k1": oldKey, "k2": newKey}))
+
+	if b, err := rotating.Load(t.Context(), 1); err != nil || string(b) != "one" {
+		t.Fatalf("Expected old record to be readable, found %q (error: %v)", b, err)
+	}
+
+	n, err := rotating.(session.Rotator).Rotate(t.Context())
+	if err != nil || n != 2 {
+		t.Fatalf("Expected %d rotated records, found %d (error: %v)", 2, n, err)
+	}
+
+	current := session.NewEncryptedStore(backend, mustKeyring(t, "k2", map[string][]byte{"k2": newKey}))
+	if b, err := current.Load(t.Context(), 2); err != nil || string(b) != "two" {
+		t.Errorf("Expected rotated record to be readable with new key, found %q (error: %v)", b, err)
+	}
+
+	if _, err := old.Load(t.Context(), 2); !errors.Is(err, session.ErrUnknownKey) {
+		t.Errorf("Should return error type %v. Got error %v", session.ErrUnknownKey, err)
+	}
+}
+
+// Store saving a concurrent update for id the first time it is loaded.
+type racingStore struct {
+	*tgbotapp.MemoryStore
+	onLoad func()
+}
+
+func (s *racingStore) LoadVersion(ctx context.Context, id int64) ([]byte, uint64, error) {
+	b, version, err := s.MemoryStore.LoadVersion(ctx, id)
+	if s.onLoad != nil {
+		f := s.onLoad
+		s.onLoad = nil
+		f()
+	}
+	return b, version, err
+}
+
+func TestEncryptedStoreRotateShouldNotOverwriteConcurrentSaves(t *testing.T) {
+	backend := &racingStore{MemoryStore: tgbotapp.NewMemoryStore()}
+	old := session.NewEncryptedStore(backend, mustKeyring(t, "k1", map[string][]byte{"k1": oldKey}))
+	old.Save(t.Context(), 1, []byte("stale"))
+
+	rotating := session.NewEncryptedStore(backend, mustKeyring(t, "k2", map[string][]byte{"k1": oldKey, "k2": newKey}))
+	backend.onLoad = func() {
+		vs := rotating.(session.VersionedStore[int64])
+		_, version, _ := vs.LoadVersion(t.Context(), 1)
+		if _, err := vs.CompareAndSave(t.Context(), 1, []byte("fresh"), version); err != nil {
+			t.Errorf("Should not return error. Got error: %v", err)
+		}
+	}
+
+	n, err := rotating.(session.Rotator).Rotate(t.Context())
+	if err != nil || n != 0 {
+		t.Fatalf("Expected %d rotated records, found %d (error: %v)", 0, n, err)
+	}
+
+	if b, err := rotating.Load(t.Context(), 1); err != nil || string(b) != "fresh" {
+		t.Errorf("Expected concurrent save to be kept, found %q (error: %v)", b, err)
+	}
+}
@@ -0,0 +1,157 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	ErrNotEnumerable = errors.New("Session manager cannot enumerate sessions.")
+)
+
+// exportVersion is the version of the export format written by Export.
+const exportVersion = 1
+
+// Enumerable is implemented by session managers that can list their sessions.
+// Range calls f for every stored session until f returns false. Sessions
+// passed to f are for reading only.
+type Enumerable[K comparable] interface {
+	Range(ctx context.Context, f func(id K, session Sessioner) bool) error
+}
+
+// KeyLister is implemented by stores that can list the ids they hold.
+type KeyLister[K comparable] interface {
+	Keys(ctx context.Context) ([]K, error)
+}
+
+// Finder is implemented by session managers that can load a single session
+// without creating it. found is false if no session is stored for id.
+type Finder[K comparable] interface {
+	Find(ctx context.Context, id K) (session Sessioner, found bool, err error)
+}
+
+type exportFile[K comparable] struct {
+	Version  int                  `json:"version"`
+	Sessions []exportedSession[K] `json:"sessions"`
+}
+
+type exportedSession[K comparable] struct {
+	ID K `json:"id"`
+	encodedSnapshot
+}
+
+// Inspect returns a snapshot of the session stored for id without creating it.
+// Managers implementing Finder load the session directly; others are scanned
+// with Range.
+func Inspect[K comparable](ctx context.Context, m Enumerable[K], id K) (snap Snapshot, found bool, err error) {
+	if f, ok := m.(Finder[K]); ok {
+		s, found, err := f.Find(ctx, id)
+		if err != nil || !found {
+			return Snapshot{}, false, err
+		}
+		return TakeSnapshot(s), true, nil
+	}
+
+	err = m.Range(ctx, func(k K, s Sessioner) bool {
+		if k != id {
+			return true
+		}
+		snap, found = TakeSnapshot(s), true
+		return false
+	})
+	return
+}
+
+// Export writes all sessions of m to w as JSON. If codecs is nil,
+// DefaultCodecs is used.
+func Export[K comparable](ctx context.Context, m Enumerable[K], w io.Writer, codecs *CodecRegistry) error {
+	if codecs == nil {
+		codecs = DefaultCodecs
+	}
+
+	file := exportFile[K]{Version: exportVersion}
+
+	var encErr error
+	err := m.Range(ctx, func(id K, s Sessioner) bool {
+		enc, err := codecs.encodeSnapshot(TakeSnapshot(s))
+		if err != nil {
+			encErr = fmt.Errorf("session %v: %w", id, err)
+			return false
+		}
+
+		file.Sessions = append(file.Sessions, exportedSession[K]{
+			ID:              id,
+			encodedSnapshot: enc,
+		})
+		return true
+	})
+	if err = errors.Join(err, encErr); err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(file)
+}
+
+// Import reads sessions written by Export and saves them into m, replacing
+// sessions with the same id. It returns the number of imported sessions.
+func Import[K comparable](ctx context.Context, m ContextSessionManager[K], r io.Reader, codecs *CodecRegistry) (int, error) {
+	if codecs == nil {
+		codecs = DefaultCodecs
+	}
+
+	var file exportFile[K]
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return 0, err
+	}
+
+	if file.Version != exportVersion {
+		return 0, fmt.Errorf("session: unsupported export version %d", file.Version)
+	}
+
+	for i, es := range file.Sessions {
+		snap, err := codecs.decodeSnapshot(es.encodedSnapshot)
+		if err != nil {
+			return i, fmt.Errorf("session %v: %w", es.ID, err)
+		}
+
+		if err = restoreInto(ctx, m, es.ID, snap); err != nil {
+			return i, fmt.Errorf("session %v: %w", es.ID, err)
+		}
+	}
+
+	return len(file.Sessions), nil
+}
+
+// Migrate copies every session of src into dst and returns the number of
+// copied sessions. Sessions in dst with the same id are replaced.
+func Migrate[K comparable](ctx context.Context, src Enumerable[K], dst ContextSessionManager[K]) (int, error) {
+	n := 0
+
+	var copyErr error
+	err := src.Range(ctx, func(id K, s Sessioner) bool {
+		if copyErr = restoreInto(ctx, dst, id, TakeSnapshot(s)); copyErr != nil {
+			copyErr = fmt.Errorf("session %v: %w", id, copyErr)
+			return false
+		}
+		n++
+		return true
+	})
+
+	return n, errors.Join(err, copyErr)
+}
+
+func restoreInto[K comparable](ctx context.Context, m ContextSessionManager[K], id K, snap Snapshot) error {
+	s, err := m.GetOrCreateContext(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	Silently(s, func() { snap.Restore(s) })
+
+	return m.SetContext(ctx, id, s)
+}
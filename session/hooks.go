@@ -0,0 +1,64 @@
+package session
+
+// Hooks are called on session lifecycle events. Nil hooks are skipped.
+// Hooks run synchronously on the goroutine that caused the event and must not
+// call back into the session that triggered them.
+type Hooks[K comparable] struct {
+	// A new session was stored for id for the first time. Sessions that are
+	// handed out but never saved do not trigger it.
+	OnCreate func(id K, session Sessioner)
+	// The state of the session for id changed through SetState.
+	OnStateChange func(id K, oldState, newState string)
+	// The data of the session for id was cleared through ClearData.
+	OnClear func(id K)
+	// The session for id was deleted from the manager. Deleting an id
+	// without a session does not trigger it.
+	OnDelete func(id K)
+}
+
+// Hookable is implemented by session managers that support lifecycle hooks.
+type Hookable[K comparable] interface {
+	SetHooks(hooks Hooks[K])
+}
+
+// Observable is implemented by sessions that notify listeners about changes.
+type Observable interface {
+	OnStateChange(f func(oldState, newState string))
+	OnClear(f func())
+}
+
+// Muter is implemented by Observable sessions whose listeners can be paused.
+type Muter interface {
+	// Stop notifying listeners until unmute is called.
+	Mute() (unmute func())
+}
+
+// Silently calls f without notifying the listeners of s, if s implements
+// Muter. It is used to restore sessions without firing their hooks.
+func Silently(s Sessioner, f func()) {
+	if m, ok := s.(Muter); ok {
+		defer m.Mute()()
+	}
+	f()
+}
+
+// Observe attaches the state change and clear hooks of h for id to s, if s is
+// Observable. The hooks are read through get on every event, so later changes
+// to the manager's hooks apply to sessions that were already handed out.
+func Observe[K comparable](s Sessioner, id K, get func() Hooks[K]) {
+	o, ok := s.(Observable)
+	if !ok {
+		return
+	}
+
+	o.OnStateChange(func(oldState, newState string) {
+		if h := get(); h.OnStateChange != nil {
+			h.OnStateChange(id, oldState, newState)
+		}
+	})
+	o.OnClear(func() {
+		if h := get(); h.OnClear != nil {
+			h.OnClear(id)
+		}
+	})
+}
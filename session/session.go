@@ -1,5 +1,14 @@
 package session
 
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrVersionConflict = errors.New("Session was modified concurrently.")
+)
+
 type Sessioner interface {
 	Get(key string) (value any, ok bool)
 	Set(key string, value any)
@@ -10,8 +19,63 @@ type Sessioner interface {
 	ClearData()
 }
 
+// SessionManager loads and saves sessions.
+//
+// GetOrCreate loads the session for id, or returns a new empty one if none is
+// stored. Set saves session as the current value for id, whether or not it
+// was stored before. Delete removes the session for id. Managers mark sessions
+// implementing DirtyTracker as clean after loading and after a successful save.
 type SessionManager[K comparable] interface {
 	GetOrCreate(id K) (Sessioner, error)
 	Set(id K, session Sessioner) error
 	Delete(id K) error
 }
+
+// DirtyTracker is implemented by sessions that know whether they were modified
+// since they were loaded or last saved. Sessions that do not implement it are
+// always saved.
+type DirtyTracker interface {
+	IsDirty() bool
+	MarkClean()
+}
+
+// Destroyer is implemented by sessions that can be marked for deletion. The
+// session middleware deletes destroyed sessions instead of saving them.
+type Destroyer interface {
+	Destroy()
+	IsDestroyed() bool
+}
+
+// IsDirty reports whether s needs to be saved.
+func IsDirty(s Sessioner) bool {
+	if d, ok := s.(DirtyTracker); ok {
+		return d.IsDirty()
+	}
+	return true
+}
+
+// MarkClean marks s as saved if it tracks modifications.
+func MarkClean(s Sessioner) {
+	if d, ok := s.(DirtyTracker); ok {
+		d.MarkClean()
+	}
+}
+
+// Locker is implemented by session managers that can serialize access to a
+// single session. The returned function releases the lock.
+type Locker[K comparable] interface {
+	Lock(ctx context.Context, id K) (unlock func(), err error)
+}
+
+// Versioned is implemented by sessions that carry the version they were loaded with.
+type Versioned interface {
+	Version() uint64
+	SetVersion(version uint64)
+}
+
+// CompareAndSetter is implemented by session managers that support optimistic
+// saves. CompareAndSet stores session only if the stored version still equals
+// the session's version, and returns ErrVersionConflict otherwise.
+type CompareAndSetter[K comparable] interface {
+	CompareAndSet(ctx context.Context, id K, session Sessioner) error
+}
@@ -0,0 +1,160 @@
+package session
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// Snapshot is a point-in-time copy of a session's state and data. Stack is
+// only set for sessions implementing StateStack that have more than one frame
+// or frame data.
+type Snapshot struct {
+	State string
+	Data  map[string]any
+	Stack []Frame
+}
+
+type encodedSnapshot struct {
+	State string                  `json:"state,omitempty"`
+	Data  map[string]EncodedValue `json:"data,omitempty"`
+	Stack []encodedFrame          `json:"stack,omitempty"`
+}
+
+type encodedFrame struct {
+	State string                  `json:"state,omitempty"`
+	Data  map[string]EncodedValue `json:"data,omitempty"`
+}
+
+// TakeSnapshot copies the state and data of s.
+func TakeSnapshot(s Sessioner) Snapshot {
+	snap := Snapshot{
+		State: s.CurrentState(),
+		Data:  make(map[string]any),
+	}
+
+	for _, k := range s.GetAllKeys() {
+		if v, ok := s.Get(k); ok {
+			snap.Data[k] = v
+		}
+	}
+
+	if st, ok := s.(StateStack); ok {
+		frames := st.Frames()
+		if len(frames) > 1 || len(frames) > 0 && len(frames[0].Data) > 0 {
+			snap.Stack = frames
+		}
+	}
+
+	return snap
+}
+
+// Restore replaces the state and data of s with the snapshot.
+func (snap Snapshot) Restore(s Sessioner) {
+	s.ClearData()
+	for k, v := range snap.Data {
+		s.Set(k, v)
+	}
+
+	if st, ok := s.(StateStack); ok && len(snap.Stack) > 0 {
+		st.SetFrames(snap.Stack)
+		return
+	}
+
+	if st, ok := s.(StateStack); ok {
+		st.Reset()
+	}
+	s.SetState(snap.State)
+}
+
+// Rebase applies to s the changes that lead from base to changed: keys whose
+// value changed are set, keys that were removed are deleted, and the states
+// are replaced if they changed. Other keys of s are kept. Values are compared
+// with reflect.DeepEqual, so values modified in place are not seen as changed.
+func Rebase(s Sessioner, base, changed Snapshot) {
+	for k, v := range changed.Data {
+		if old, ok := base.Data[k]; !ok || !reflect.DeepEqual(old, v) {
+			s.Set(k, v)
+		}
+	}
+	for k := range base.Data {
+		if _, ok := changed.Data[k]; !ok {
+			s.Delete(k)
+		}
+	}
+
+	if changed.State == base.State && reflect.DeepEqual(changed.Stack, base.Stack) {
+		return
+	}
+	if st, ok := s.(StateStack); ok {
+		if len(changed.Stack) > 0 {
+			st.SetFrames(changed.Stack)
+			return
+		}
+		st.Reset()
+	}
+	s.SetState(changed.State)
+}
+
+// Marshal encodes snap to JSON using the registry's codecs.
+func (r *CodecRegistry) Marshal(snap Snapshot) ([]byte, error) {
+	enc, err := r.encodeSnapshot(snap)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(enc)
+}
+
+func (r *CodecRegistry) encodeSnapshot(snap Snapshot) (encodedSnapshot, error) {
+	data, err := r.EncodeData(snap.Data)
+	if err != nil {
+		return encodedSnapshot{}, err
+	}
+
+	enc := encodedSnapshot{
+		State: snap.State,
+		Data:  data,
+	}
+
+	for _, f := range snap.Stack {
+		fd, err := r.EncodeData(f.Data)
+		if err != nil {
+			return encodedSnapshot{}, err
+		}
+		enc.Stack = append(enc.Stack, encodedFrame{State: f.State, Data: fd})
+	}
+
+	return enc, nil
+}
+
+// Unmarshal decodes a snapshot produced by Marshal.
+func (r *CodecRegistry) Unmarshal(b []byte) (Snapshot, error) {
+	var enc encodedSnapshot
+	if err := json.Unmarshal(b, &enc); err != nil {
+		return Snapshot{}, err
+	}
+
+	return r.decodeSnapshot(enc)
+}
+
+func (r *CodecRegistry) decodeSnapshot(enc encodedSnapshot) (Snapshot, error) {
+	data, err := r.DecodeData(enc.Data)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	snap := Snapshot{
+		State: enc.State,
+		Data:  data,
+	}
+
+	for _, ef := range enc.Stack {
+		fd, err := r.DecodeData(ef.Data)
+		if err != nil {
+			return Snapshot{}, err
+		}
+		snap.Stack = append(snap.Stack, Frame{State: ef.State, Data: fd})
+	}
+
+	return snap, nil
+}
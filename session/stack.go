@@ -0,0 +1,35 @@
+package session
+
+// Frame is one entry of a session's state stack. Data is scratch data that
+// belongs to the frame and is discarded when the frame is popped.
+type Frame struct {
+	State string
+	Data  map[string]any
+}
+
+// StateStack is implemented by sessions that keep a stack of states instead
+// of a single one. The stack always has at least one frame. CurrentState
+// returns the state of the top frame and SetState replaces it.
+type StateStack interface {
+	// Push a new frame with state on top of the stack.
+	Push(state string)
+	// Pop the top frame and return the state that is current afterwards.
+	// ok is false if only the bottom frame is left.
+	Pop() (state string, ok bool)
+	// Replace the state of the top frame, keeping its data.
+	Replace(state string)
+	// Return the state of the top frame.
+	Peek() string
+	// Drop all frames and leave a single empty one.
+	Reset()
+	// Return the number of frames.
+	Depth() int
+
+	FrameGet(key string) (value any, ok bool)
+	FrameSet(key string, value any)
+
+	// Return a copy of all frames, bottom first.
+	Frames() []Frame
+	// Replace all frames, bottom first.
+	SetFrames(frames []Frame)
+}
@@ -0,0 +1,27 @@
+package session
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrNotFound = errors.New("Session not found.")
+)
+
+// Store is a byte level backend for persistent session managers.
+// Load returns ErrNotFound if nothing is stored for id.
+type Store[K comparable] interface {
+	Load(ctx context.Context, id K) ([]byte, error)
+	Save(ctx context.Context, id K, data []byte) error
+	Delete(ctx context.Context, id K) error
+}
+
+// VersionedStore is a Store that supports optimistic concurrency. Version 0
+// means that nothing is stored. CompareAndSave returns ErrVersionConflict if
+// the stored version is not version, and the new version otherwise.
+type VersionedStore[K comparable] interface {
+	Store[K]
+	LoadVersion(ctx context.Context, id K) (data []byte, version uint64, err error)
+	CompareAndSave(ctx context.Context, id K, data []byte, version uint64) (uint64, error)
+}
@@ -0,0 +1,103 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Key is a typed session key. It binds a session data key to the type of the
+// value stored under it.
+type Key[T any] struct {
+	name string
+}
+
+func NewKey[T any](name string) Key[T] {
+	return Key[T]{name: name}
+}
+
+func (k Key[T]) Name() string {
+	return k.name
+}
+
+func (k Key[T]) Get(s Sessioner) (T, bool) {
+	return Get[T](s, k.name)
+}
+
+func (k Key[T]) MustGet(s Sessioner) T {
+	return MustGet[T](s, k.name)
+}
+
+func (k Key[T]) GetOr(s Sessioner, fallback T) T {
+	return GetOr(s, k.name, fallback)
+}
+
+func (k Key[T]) Set(s Sessioner, value T) {
+	s.Set(k.name, value)
+}
+
+func (k Key[T]) Delete(s Sessioner) {
+	s.Delete(k.name)
+}
+
+// Get returns the value stored under key as T.
+//
+// Values that do not hold T directly (e.g. float64 or map[string]any coming back
+// from a JSON backed store) are converted through their JSON representation.
+// ok is false if the key is missing or the value cannot be converted.
+func Get[T any](s Sessioner, key string) (value T, ok bool) {
+	if s == nil {
+		return
+	}
+
+	raw, found := s.Get(key)
+	if !found {
+		return
+	}
+
+	value, err := Convert[T](raw)
+	if err != nil {
+		return
+	}
+
+	return value, true
+}
+
+// MustGet is like Get but panics if the key is missing or has an incompatible type.
+func MustGet[T any](s Sessioner, key string) T {
+	value, ok := Get[T](s, key)
+	if !ok {
+		panic(fmt.Sprintf("session: key %q is missing or is not of type %T", key, value))
+	}
+	return value
+}
+
+// GetOr is like Get but returns fallback if the key is missing or has an incompatible type.
+func GetOr[T any](s Sessioner, key string, fallback T) T {
+	value, ok := Get[T](s, key)
+	if !ok {
+		return fallback
+	}
+	return value
+}
+
+// Convert returns raw as T, converting it through JSON if it is not already T.
+func Convert[T any](raw any) (value T, err error) {
+	if v, ok := raw.(T); ok {
+		return v, nil
+	}
+
+	if raw == nil {
+		return value, fmt.Errorf("session: cannot convert nil to %T", value)
+	}
+
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return value, err
+	}
+
+	if err = json.Unmarshal(b, &value); err != nil {
+		return value, fmt.Errorf("session: cannot convert %T to %T: %w", raw, value, err)
+	}
+
+	return value, nil
+}
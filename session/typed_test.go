@@ -0,0 +1,117 @@
+package session_test
+
+import (
+	"testing"
+
+	tgbotapp "github.com/nexoratech2025/go-telegram-bot-app"
+	"github.com/nexoratech2025/go-telegram-bot-app/session"
+	"github.com/nexoratech2025/go-telegram-bot-app/testutil"
+)
+
+type address struct {
+	City string `json:"city"`
+	Zip  string `json:"zip"`
+}
+
+func TestGetShouldReturnTypedValue(t *testing.T) {
+	s := tgbotapp.NewDefaultSession()
+	s.Set("count", 42)
+
+	v, ok := session.Get[int](s, "count")
+	if !ok || v != 42 {
+		t.Errorf("Expected 42, found %d (ok=%v)", v, ok)
+	}
+}
+
+func TestGetShouldConvertJSONDecodedValues(t *testing.T) {
+	s := tgbotapp.NewDefaultSession()
+	s.Set("count", float64(42))
+	s.Set("addr", map[string]any{"city": "Yangon", "zip": "11181"})
+
+	n, ok := session.Get[int](s, "count")
+	if !ok || n != 42 {
+		t.Errorf("Expected 42, found %d (ok=%v)", n, ok)
+	}
+
+	a, ok := session.Get[address](s, "addr")
+	if !ok || a.City != "Yangon" {
+		t.Errorf("Expected converted address, found %#v (ok=%v)", a, ok)
+	}
+}
+
+func TestGetOrShouldReturnFallbackForWrongType(t *testing.T) {
+	s := tgbotapp.NewDefaultSession()
+	s.Set("name", "john")
+
+	if v := session.GetOr(s, "name", 7); v != 7 {
+		t.Errorf("Expected fallback 7, found %d", v)
+	}
+	if v := session.GetOr(s, "missing", "x"); v != "x" {
+		t.Errorf("Expected fallback %q, found %q", "x", v)
+	}
+}
+
+func TestMustGetShouldPanicForMissingKey(t *testing.T) {
+	s := tgbotapp.NewDefaultSession()
+
+	testutil.AssertPanic(t, func() {
+		session.MustGet[string](s, "missing")
+	})
+}
+
+func TestKeyShouldSetAndGet(t *testing.T) {
+	s := tgbotapp.NewDefaultSession()
+	key := session.NewKey[address]("addr")
+
+	key.Set(s, address{City: "Mandalay"})
+
+	a, ok := key.Get(s)
+	if !ok || a.City != "Mandalay" {
+		t.Errorf("Expected Mandalay, found %#v (ok=%v)", a, ok)
+	}
+}
+
+func TestCodecRegistryShouldRoundTripRegisteredTypes(t *testing.T) {
+	r := session.NewCodecRegistry()
+	if err := session.RegisterCodec[address](r, "address", session.JSONCodec[address]{}); err != nil {
+		t.Fatalf("Should not return error. Got error: %v", err)
+	}
+
+	b, err := r.Marshal(session.Snapshot{
+		State: "checkout",
+		Data: map[string]any{
+			"count": 3,
+			"addr":  address{City: "Bago"},
+			"id":    int64(1) << 53,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Should not return error. Got error: %v", err)
+	}
+
+	snap, err := r.Unmarshal(b)
+	if err != nil {
+		t.Fatalf("Should not return error. Got error: %v", err)
+	}
+
+	if snap.State != "checkout" {
+		t.Errorf("Expected state %q, found %q", "checkout", snap.State)
+	}
+	if _, ok := snap.Data["count"].(int); !ok {
+		t.Errorf("Expected int, found %T", snap.Data["count"])
+	}
+	if a, ok := snap.Data["addr"].(address); !ok || a.City != "Bago" {
+		t.Errorf("Expected address, found %#v", snap.Data["addr"])
+	}
+	if id, ok := snap.Data["id"].(int64); !ok || id != int64(1)<<53 {
+		t.Errorf("Expected int64 %d, found %#v", int64(1)<<53, snap.Data["id"])
+	}
+}
+
+func TestRegisterCodecShouldRejectDuplicates(t *testing.T) {
+	r := session.NewCodecRegistry()
+
+	if err := session.RegisterCodec[int](r, "other_int", session.JSONCodec[int]{}); err == nil {
+		t.Error("Should return error. got no error")
+	}
+}
@@ -0,0 +1,312 @@
+package tgbotapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/nexoratech2025/go-telegram-bot-app/session"
+)
+
+// StoreManager is a session manager that persists sessions into a byte store.
+// Values are encoded with a codec registry so that they come back with the
+// type they were stored with.
+type StoreManager struct {
+	store  session.Store[int64]
+	codecs *session.CodecRegistry
+	hooks  session.Hooks[int64]
+	mu     sync.RWMutex
+}
+
+// Return new manager on top of store. If codecs is nil, session.DefaultCodecs is used.
+func NewStoreManager(store session.Store[int64], codecs *session.CodecRegistry) *StoreManager {
+	if codecs == nil {
+		codecs = session.DefaultCodecs
+	}
+
+	return &StoreManager{
+		store:  store,
+		codecs: codecs,
+	}
+}
+
+func (m *StoreManager) GetOrCreate(chatID int64) (session.Sessioner, error) {
+	return m.GetOrCreateContext(context.Background(), chatID)
+}
+
+func (m *StoreManager) Set(chatID int64, sess session.Sessioner) error {
+	return m.SetContext(context.Background(), chatID, sess)
+}
+
+func (m *StoreManager) Delete(chatID int64) error {
+	return m.DeleteContext(context.Background(), chatID)
+}
+
+// GetOrCreateContext implements session.ContextSessionManager.
+func (m *StoreManager) GetOrCreateContext(ctx context.Context, chatID int64) (session.Sessioner, error) {
+	sess := NewDefaultSession()
+
+	b, version, err := m.load(ctx, chatID)
+	if errors.Is(err, session.ErrNotFound) {
+		// OnCreate fires once the session is first saved.
+		sess.(*DefaultSession).unsaved = true
+		observeSession(sess, chatID, m.getHooks)
+		return sess, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if v, ok := sess.(session.Versioned); ok {
+		v.SetVersion(version)
+	}
+
+	snap, err := m.codecs.Unmarshal(b)
+	if err != nil {
+		return nil, err
+	}
+
+	snap.Restore(sess)
+	session.MarkClean(sess)
+	observeSession(sess, chatID, m.getHooks)
+
+	return sess, nil
+}
+
+// SetContext implements session.ContextSessionManager.
+func (m *StoreManager) SetContext(ctx context.Context, chatID int64, sess session.Sessioner) error {
+	b, err := m.codecs.Marshal(session.TakeSnapshot(sess))
+	if err != nil {
+		return err
+	}
+
+	if err = m.store.Save(ctx, chatID, b); err != nil {
+		return err
+	}
+
+	session.MarkClean(sess)
+	m.created(chatID, sess)
+	return nil
+}
+
+// Fire OnCreate if sess was created by the manager and just got stored.
+func (m *StoreManager) created(chatID int64, sess session.Sessioner) {
+	if d, ok := sess.(*DefaultSession); !ok || !d.takeUnsaved() {
+		return
+	}
+	if h := m.getHooks(); h.OnCreate != nil {
+		h.OnCreate(chatID, sess)
+	}
+}
+
+// DeleteContext implements session.ContextSessionManager. OnDelete fires
+// only if a session was stored for chatID.
+func (m *StoreManager) DeleteContext(ctx context.Context, chatID int64) error {
+	_, _, err := m.load(ctx, chatID)
+	stored := err == nil
+	if err != nil && !errors.Is(err, session.ErrNotFound) {
+		return err
+	}
+
+	if err := m.store.Delete(ctx, chatID); err != nil {
+		return err
+	}
+
+	if h := m.getHooks(); stored && h.OnDelete != nil {
+		h.OnDelete(chatID)
+	}
+	return nil
+}
+
+// Range implements session.Enumerable. The store must implement
+// session.KeyLister, otherwise session.ErrNotEnumerable is returned.
+func (m *StoreManager) Range(ctx context.Context, f func(chatID int64, sess session.Sessioner) bool) error {
+	lister, ok := m.store.(session.KeyLister[int64])
+	if !ok {
+		return session.ErrNotEnumerable
+	}
+
+	keys, err := lister.Keys(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, chatID := range keys {
+		b, _, err := m.load(ctx, chatID)
+		if errors.Is(err, session.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		sess, err := m.decode(b)
+		if err != nil {
+			return fmt.Errorf("session %d: %w", chatID, err)
+		}
+
+		if !f(chatID, sess) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// Find implements session.Finder.
+func (m *StoreManager) Find(ctx context.Context, chatID int64) (session.Sessioner, bool, error) {
+	b, _, err := m.load(ctx, chatID)
+	if errors.Is(err, session.ErrNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	sess, err := m.decode(b)
+	if err != nil {
+		return nil, false, err
+	}
+	return sess, true, nil
+}
+
+// Return a clean session holding the encoded snapshot b, without hooks.
+func (m *StoreManager) decode(b []byte) (session.Sessioner, error) {
+	snap, err := m.codecs.Unmarshal(b)
+	if err != nil {
+		return nil, err
+	}
+
+	sess := NewDefaultSession()
+	snap.Restore(sess)
+	session.MarkClean(sess)
+	return sess, nil
+}
+
+// SetHooks implements session.Hookable.
+func (m *StoreManager) SetHooks(hooks session.Hooks[int64]) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = hooks
+}
+
+func (m *StoreManager) getHooks() session.Hooks[int64] {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.hooks
+}
+
+// CompareAndSet implements session.CompareAndSetter. It requires the store to
+// implement session.VersionedStore and falls back to SetContext otherwise.
+func (m *StoreManager) CompareAndSet(ctx context.Context, chatID int64, sess session.Sessioner) error {
+	vs, ok := m.store.(session.VersionedStore[int64])
+	v, versioned := sess.(session.Versioned)
+	if !ok || !versioned {
+		return m.SetContext(ctx, chatID, sess)
+	}
+
+	b, err := m.codecs.Marshal(session.TakeSnapshot(sess))
+	if err != nil {
+		return err
+	}
+
+	version, err := vs.CompareAndSave(ctx, chatID, b, v.Version())
+	if err != nil {
+		return err
+	}
+
+	v.SetVersion(version)
+	session.MarkClean(sess)
+	m.created(chatID, sess)
+	return nil
+}
+
+func (m *StoreManager) load(ctx context.Context, chatID int64) ([]byte, uint64, error) {
+	if vs, ok := m.store.(session.VersionedStore[int64]); ok {
+		return vs.LoadVersion(ctx, chatID)
+	}
+
+	b, err := m.store.Load(ctx, chatID)
+	return b, 0, err
+}
+
+// In memory implementation of session.VersionedStore. Mainly useful for tests.
+type MemoryStore struct {
+	data map[int64]memoryEntry
+	mu   sync.RWMutex
+}
+
+type memoryEntry struct {
+	data    []byte
+	version uint64
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		data: make(map[int64]memoryEntry),
+	}
+}
+
+func (s *MemoryStore) Load(ctx context.Context, chatID int64) ([]byte, error) {
+	b, _, err := s.LoadVersion(ctx, chatID)
+	return b, err
+}
+
+func (s *MemoryStore) LoadVersion(ctx context.Context, chatID int64) ([]byte, uint64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	e, ok := s.data[chatID]
+	if !ok {
+		return nil, 0, session.ErrNotFound
+	}
+
+	return append([]byte(nil), e.data...), e.version, nil
+}
+
+func (s *MemoryStore) Save(ctx context.Context, chatID int64, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data[chatID] = memoryEntry{
+		data:    append([]byte(nil), data...),
+		version: s.data[chatID].version + 1,
+	}
+	return nil
+}
+
+func (s *MemoryStore) CompareAndSave(ctx context.Context, chatID int64, data []byte, version uint64) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.data[chatID].version != version {
+		return 0, session.ErrVersionConflict
+	}
+
+	e := memoryEntry{
+		data:    append([]byte(nil), data...),
+		version: version + 1,
+	}
+	s.data[chatID] = e
+
+	return e.version, nil
+}
+
+// Keys implements session.KeyLister.
+func (s *MemoryStore) Keys(ctx context.Context) ([]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return slices.Collect(maps.Keys(s.data)), nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, chatID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.data, chatID)
+	return nil
+}
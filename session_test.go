@@ -1,9 +1,18 @@
 package tgbotapp_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"runtime"
+	"slices"
+	"sync"
 	"testing"
 
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	tgbotapp "github.com/nexoratech2025/go-telegram-bot-app"
+	"github.com/nexoratech2025/go-telegram-bot-app/session"
 )
 
 func TestGetOrCreateSessionShouldCreateNewSessionIfNotExists(t *testing.T) {
@@ -96,3 +105,603 @@ func TestSetStateShouldUpdateSessionState(t *testing.T) {
 	}
 
 }
+
+func TestStoreManagerShouldRoundTripTypedValues(t *testing.T) {
+	// Arrange
+	mgr := tgbotapp.NewStoreManager(tgbotapp.NewMemoryStore(), nil)
+	const chatID int64 = 123
+	s, _ := mgr.GetOrCreate(chatID)
+	s.Set("count", 7)
+	s.SetState("TEST_STATE")
+
+	// Act
+	err := mgr.Set(chatID, s)
+
+	// Assert
+	if err != nil {
+		t.Errorf(expectsNoError, err)
+	}
+
+	s, err = mgr.GetOrCreate(chatID)
+	if err != nil {
+		t.Errorf(expectsNoError, err)
+	}
+
+	if s.CurrentState() != "TEST_STATE" {
+		t.Errorf("Expected state to be %q, found state %q", "TEST_STATE", s.CurrentState())
+	}
+
+	v, _ := s.Get("count")
+	if n, ok := v.(int); !ok || n != 7 {
+		t.Errorf("Expected int 7, found %#v", v)
+	}
+
+}
+
+func newChatUpdate(chatID int64, text string) *tgbotapi.Update {
+	return &tgbotapi.Update{
+		Message: &tgbotapi.Message{
+			Chat: &tgbotapi.Chat{ID: chatID, Type: "private"},
+			From: &tgbotapi.User{ID: chatID},
+			Text: text,
+		},
+	}
+}
+
+func TestCompareAndSetShouldReturnConflictForStaleSession(t *testing.T) {
+	// Arrange
+	mgr := tgbotapp.NewStoreManager(tgbotapp.NewMemoryStore(), nil)
+	const chatID int64 = 123
+
+	first, _ := mgr.GetOrCreate(chatID)
+	second, _ := mgr.GetOrCreate(chatID)
+
+	first.Set("winner", "first")
+	if err := mgr.CompareAndSet(t.Context(), chatID, first); err != nil {
+		t.Fatalf(expectsNoError, err)
+	}
+
+	// Act
+	second.Set("winner", "second")
+	err := mgr.CompareAndSet(t.Context(), chatID, second)
+
+	// Assert
+	if !errors.Is(err, session.ErrVersionConflict) {
+		t.Errorf(expectsErrorType, session.ErrVersionConflict, err)
+	}
+}
+
+func TestSessionMiddlewareShouldRetrySaveOnConflictWithoutRerunningHandler(t *testing.T) {
+	// Arrange
+	const chatID int64 = 123
+	mgr := tgbotapp.NewStoreManager(tgbotapp.NewMemoryStore(), nil)
+	app := &tgbotapp.Application{Logger: slog.Default()}
+	m := tgbotapp.SessionMiddleware(mgr, tgbotapp.WithConflictRetry(3, 0))
+
+	calls := 0
+	handler := func(ctx *tgbotapp.BotContext) {
+		calls++
+		n, _ := session.Get[int](ctx.Session, "count")
+		ctx.Session.Set("count", n+1)
+		ctx.Session.SetState("counting")
+
+		// Simulate a concurrent update for the same chat.
+		other, _ := mgr.GetOrCreate(chatID)
+		other.Set("other", 10)
+		mgr.CompareAndSet(t.Context(), chatID, other)
+	}
+
+	// Act
+	m(tgbotapp.NewBotContext(t.Context(), app, newChatUpdate(chatID, "hi")), handler)
+
+	// Assert
+	if calls != 1 {
+		t.Errorf("Expected handler to run %d time, ran %d times", 1, calls)
+	}
+
+	s, _ := mgr.GetOrCreate(chatID)
+	if n, _ := session.Get[int](s, "count"); n != 1 {
+		t.Errorf("Expected count %d, found %d", 1, n)
+	}
+	if n, _ := session.Get[int](s, "other"); n != 10 {
+		t.Errorf("Expected other %d, found %d", 10, n)
+	}
+	if s.CurrentState() != "counting" {
+		t.Errorf("Expected state %q, found %q", "counting", s.CurrentState())
+	}
+}
+
+func TestInMemoryManagerShouldSerializeUpdatesForSameChat(t *testing.T) {
+	// Arrange
+	const chatID int64 = 123
+	mgr := tgbotapp.NewDefaultInMemoryManager()
+	app := &tgbotapp.Application{Logger: slog.Default()}
+	m := tgbotapp.SessionMiddleware(mgr)
+
+	handler := func(ctx *tgbotapp.BotContext) {
+		n, _ := session.Get[int](ctx.Session, "count")
+		runtime.Gosched()
+		ctx.Session.Set("count", n+1)
+	}
+
+	// Act
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m(tgbotapp.NewBotContext(t.Context(), app, newChatUpdate(chatID, "hi")), handler)
+		}()
+	}
+	wg.Wait()
+
+	// Assert
+	s, _ := mgr.GetOrCreate(chatID)
+	if n, _ := session.Get[int](s, "count"); n != 50 {
+		t.Errorf("Expected count %d, found %d", 50, n)
+	}
+}
+
+func TestSessionMiddlewareShouldSaveOnlyDirtySessions(t *testing.T) {
+	// Arrange
+	const chatID int64 = 123
+	store := tgbotapp.NewMemoryStore()
+	mgr := tgbotapp.NewStoreManager(store, nil)
+	app := &tgbotapp.Application{Logger: slog.Default()}
+	m := tgbotapp.SessionMiddleware(mgr)
+
+	m(tgbotapp.NewBotContext(t.Context(), app, newChatUpdate(chatID, "hi")), func(ctx *tgbotapp.BotContext) {
+		ctx.Session.SetState("TEST_STATE")
+	})
+	_, before, _ := store.LoadVersion(t.Context(), chatID)
+
+	// Act
+	m(tgbotapp.NewBotContext(t.Context(), app, newChatUpdate(chatID, "hi")), func(ctx *tgbotapp.BotContext) {
+		ctx.Session.CurrentState()
+	})
+
+	// Assert
+	_, after, _ := store.LoadVersion(t.Context(), chatID)
+	if before != after {
+		t.Errorf("Expected clean session not to be saved. Version changed from %d to %d", before, after)
+	}
+}
+
+func TestSessionMiddlewareShouldDeleteDestroyedSession(t *testing.T) {
+	// Arrange
+	const chatID int64 = 123
+	store := tgbotapp.NewMemoryStore()
+	mgr := tgbotapp.NewStoreManager(store, nil)
+	app := &tgbotapp.Application{Logger: slog.Default()}
+	m := tgbotapp.SessionMiddleware(mgr)
+
+	m(tgbotapp.NewBotContext(t.Context(), app, newChatUpdate(chatID, "hi")), func(ctx *tgbotapp.BotContext) {
+		ctx.Session.Set("phone", "123")
+	})
+
+	// Act
+	m(tgbotapp.NewBotContext(t.Context(), app, newChatUpdate(chatID, "hi")), func(ctx *tgbotapp.BotContext) {
+		tgbotapp.NewHandlerContext(ctx, "destroy").DestroySession()
+	})
+
+	// Assert
+	if _, err := store.Load(t.Context(), chatID); !errors.Is(err, session.ErrNotFound) {
+		t.Errorf(expectsErrorType, session.ErrNotFound, err)
+	}
+}
+
+func TestSessionMiddlewareShouldReportSaveErrors(t *testing.T) {
+	// Arrange
+	const chatID int64 = 123
+	mgr := tgbotapp.NewStoreManager(tgbotapp.NewMemoryStore(), nil)
+	app := &tgbotapp.Application{Logger: slog.Default()}
+
+	var saveErr error
+	m := tgbotapp.SessionMiddleware(mgr, tgbotapp.WithSaveErrorHandler(func(ctx *tgbotapp.BotContext, err error) {
+		saveErr = err
+	}))
+
+	// Act
+	m(tgbotapp.NewBotContext(t.Context(), app, newChatUpdate(chatID, "hi")), func(ctx *tgbotapp.BotContext) {
+		ctx.Session.Set("bad", func() {})
+	})
+
+	// Assert
+	if saveErr == nil {
+		t.Error(expectsError)
+	}
+}
+
+func TestInMemoryManagerSetShouldStoreGivenSession(t *testing.T) {
+	// Arrange
+	mgr := tgbotapp.NewDefaultInMemoryManager()
+	const chatID int64 = 123
+	s := tgbotapp.NewDefaultSession()
+	s.SetState("TEST_STATE")
+
+	// Act
+	err := mgr.Set(chatID, s)
+
+	// Assert
+	if err != nil {
+		t.Errorf(expectsNoError, err)
+	}
+
+	got, _ := mgr.GetOrCreate(chatID)
+	if got.CurrentState() != "TEST_STATE" {
+		t.Errorf("Expected state to be %q, found state %q", "TEST_STATE", got.CurrentState())
+	}
+}
+
+type failingStore struct {
+	*tgbotapp.MemoryStore
+	failures int
+}
+
+func (s *failingStore) LoadVersion(ctx context.Context, chatID int64) ([]byte, uint64, error) {
+	if s.failures > 0 {
+		s.failures--
+		return nil, 0, errors.New("store unavailable")
+	}
+	return s.MemoryStore.LoadVersion(ctx, chatID)
+}
+
+func TestSessionMiddlewareShouldFailUpdateWhenLoadFails(t *testing.T) {
+	// Arrange
+	mgr := tgbotapp.NewStoreManager(&failingStore{tgbotapp.NewMemoryStore(), 1}, nil)
+	app := &tgbotapp.Application{Logger: slog.Default()}
+
+	var loadErr error
+	m := tgbotapp.SessionMiddleware(mgr,
+		tgbotapp.WithLoadErrorPolicy(tgbotapp.LoadErrorFail),
+		tgbotapp.WithLoadErrorHandler(func(ctx *tgbotapp.BotContext, err error) {
+			loadErr = err
+		}),
+	)
+
+	called := false
+
+	// Act
+	m(tgbotapp.NewBotContext(t.Context(), app, newChatUpdate(123, "hi")), func(ctx *tgbotapp.BotContext) {
+		called = true
+	})
+
+	// Assert
+	if called {
+		t.Errorf("Expected handler not to be called")
+	}
+	if loadErr == nil {
+		t.Error(expectsError)
+	}
+}
+
+func TestSessionMiddlewareShouldUseEphemeralSessionWhenLoadFailsByDefault(t *testing.T) {
+	// Arrange
+	store := &failingStore{tgbotapp.NewMemoryStore(), 1}
+	mgr := tgbotapp.NewStoreManager(store, nil)
+	app := &tgbotapp.Application{Logger: slog.Default()}
+	m := tgbotapp.SessionMiddleware(mgr)
+
+	// Act
+	m(tgbotapp.NewBotContext(t.Context(), app, newChatUpdate(123, "hi")), func(ctx *tgbotapp.BotContext) {
+		if ctx.Session == nil {
+			t.Errorf(expectsNotNil, "ctx.Session")
+			return
+		}
+		ctx.Session.Set("key", "value")
+	})
+
+	// Assert
+	if _, err := store.MemoryStore.Load(t.Context(), 123); !errors.Is(err, session.ErrNotFound) {
+		t.Errorf("Expected ephemeral session not to be saved. Got error: %v", err)
+	}
+}
+
+func TestSessionMiddlewareShouldRetryLoad(t *testing.T) {
+	// Arrange
+	mgr := tgbotapp.NewStoreManager(&failingStore{tgbotapp.NewMemoryStore(), 2}, nil)
+	app := &tgbotapp.Application{Logger: slog.Default()}
+	m := tgbotapp.SessionMiddleware(mgr, tgbotapp.WithLoadRetry(3, 0))
+
+	called := false
+
+	// Act
+	m(tgbotapp.NewBotContext(t.Context(), app, newChatUpdate(123, "hi")), func(ctx *tgbotapp.BotContext) {
+		called = true
+	})
+
+	// Assert
+	if !called {
+		t.Errorf("Expected handler to be called after retries")
+	}
+}
+
+func TestContextAdapterShouldHonorCancellation(t *testing.T) {
+	// Arrange
+	mgr := session.WithContext(tgbotapp.NewDefaultInMemoryManager())
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	// Act
+	_, err := mgr.GetOrCreateContext(ctx, 123)
+
+	// Assert
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf(expectsErrorType, context.Canceled, err)
+	}
+}
+
+func TestSessionHooksShouldReportLifecycleEvents(t *testing.T) {
+	// Arrange
+	const chatID int64 = 123
+	var events []string
+	mgr := tgbotapp.NewDefaultInMemoryManager()
+	mgr.(session.Hookable[int64]).SetHooks(session.Hooks[int64]{
+		OnCreate: func(id int64, s session.Sessioner) {
+			events = append(events, "create")
+		},
+		OnStateChange: func(id int64, oldState, newState string) {
+			events = append(events, "state:"+oldState+">"+newState)
+		},
+		OnClear: func(id int64) {
+			events = append(events, "clear")
+		},
+		OnDelete: func(id int64) {
+			events = append(events, "delete")
+		},
+	})
+
+	// Act
+	s, _ := mgr.GetOrCreate(chatID)
+	s.SetState("a")
+	s.SetState("a")
+	s.SetState("b")
+	s.Set("key", "value")
+	s.ClearData()
+	mgr.GetOrCreate(chatID)
+	mgr.Delete(chatID)
+
+	// Assert
+	expected := []string{"create", "state:>a", "state:a>b", "clear", "delete"}
+	if !slices.Equal(events, expected) {
+		t.Errorf("Expected events %v, found %v", expected, events)
+	}
+}
+
+func TestInMemoryManagerShouldObserveReaddedSessionOnce(t *testing.T) {
+	// Arrange
+	const chatID int64 = 123
+	mgr := tgbotapp.NewDefaultInMemoryManager()
+
+	changes := 0
+	mgr.(session.Hookable[int64]).SetHooks(session.Hooks[int64]{
+		OnStateChange: func(id int64, oldState, newState string) {
+			changes++
+		},
+	})
+
+	s, _ := mgr.GetOrCreate(chatID)
+	mgr.Delete(chatID)
+	mgr.Set(chatID, s)
+
+	// Act
+	s.SetState("x")
+
+	// Assert
+	if changes != 1 {
+		t.Errorf("Expected OnStateChange to fire %d time, fired %d times", 1, changes)
+	}
+}
+
+func TestManagersShouldFireOnDeleteOnlyForStoredSessions(t *testing.T) {
+	managers := map[string]session.SessionManager[int64]{
+		"memory": tgbotapp.NewDefaultInMemoryManager(),
+		"store":  tgbotapp.NewStoreManager(tgbotapp.NewMemoryStore(), nil),
+	}
+
+	for name, mgr := range managers {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			var deleted []int64
+			mgr.(session.Hookable[int64]).SetHooks(session.Hooks[int64]{
+				OnDelete: func(id int64) {
+					deleted = append(deleted, id)
+				},
+			})
+
+			s, _ := mgr.GetOrCreate(1)
+			mgr.Set(1, s)
+
+			// Act
+			mgr.Delete(1)
+			mgr.Delete(2)
+
+			// Assert
+			if !slices.Equal(deleted, []int64{1}) {
+				t.Errorf("Expected OnDelete for %v, found %v", []int64{1}, deleted)
+			}
+		})
+	}
+}
+
+func TestStoreManagerHooksShouldNotFireWhileRestoring(t *testing.T) {
+	// Arrange
+	const chatID int64 = 123
+	mgr := tgbotapp.NewStoreManager(tgbotapp.NewMemoryStore(), nil)
+	s, _ := mgr.GetOrCreate(chatID)
+	s.SetState("a")
+	mgr.Set(chatID, s)
+
+	var events []string
+	mgr.SetHooks(session.Hooks[int64]{
+		OnStateChange: func(id int64, oldState, newState string) {
+			events = append(events, oldState+">"+newState)
+		},
+	})
+
+	// Act
+	s, _ = mgr.GetOrCreate(chatID)
+	s.SetState("b")
+
+	// Assert
+	if !slices.Equal(events, []string{"a>b"}) {
+		t.Errorf("Expected events %v, found %v", []string{"a>b"}, events)
+	}
+}
+
+func TestStoreManagerShouldFireOnCreateOnceOnFirstSave(t *testing.T) {
+	// Arrange
+	const chatID int64 = 123
+	mgr := tgbotapp.NewStoreManager(tgbotapp.NewMemoryStore(), nil)
+
+	created := 0
+	mgr.SetHooks(session.Hooks[int64]{
+		OnCreate: func(id int64, s session.Sessioner) {
+			created++
+		},
+	})
+
+	// Act
+	mgr.GetOrCreate(chatID)
+	mgr.GetOrCreate(chatID)
+	s, _ := mgr.GetOrCreate(chatID)
+	s.Set("key", "value")
+	mgr.CompareAndSet(t.Context(), chatID, s)
+	s, _ = mgr.GetOrCreate(chatID)
+	s.Set("key", "other")
+	mgr.CompareAndSet(t.Context(), chatID, s)
+
+	// Assert
+	if created != 1 {
+		t.Errorf("Expected OnCreate to fire %d time, fired %d times", 1, created)
+	}
+}
+
+func TestImportShouldNotFireStateHooks(t *testing.T) {
+	// Arrange
+	src := tgbotapp.NewDefaultInMemoryManager()
+	s, _ := src.GetOrCreate(1)
+	s.SetState("checkout")
+	s.Set("items", 3)
+
+	var buf bytes.Buffer
+	if err := session.Export(t.Context(), src.(session.Enumerable[int64]), &buf, nil); err != nil {
+		t.Fatalf(expectsNoError, err)
+	}
+
+	dst := tgbotapp.NewStoreManager(tgbotapp.NewMemoryStore(), nil)
+	s, _ = dst.GetOrCreate(1)
+	s.SetState("old")
+	s.Set("items", 1)
+	dst.Set(1, s)
+
+	var events []string
+	dst.SetHooks(session.Hooks[int64]{
+		OnStateChange: func(id int64, oldState, newState string) {
+			events = append(events, "state")
+		},
+		OnClear: func(id int64) {
+			events = append(events, "clear")
+		},
+	})
+
+	// Act
+	_, err := session.Import(t.Context(), dst, &buf, nil)
+
+	// Assert
+	if err != nil {
+		t.Fatalf(expectsNoError, err)
+	}
+	if len(events) != 0 {
+		t.Errorf("Expected no hook to fire, found %v", events)
+	}
+}
+
+func TestExportImportShouldRoundTripSessions(t *testing.T) {
+	// Arrange
+	src := tgbotapp.NewDefaultInMemoryManager()
+	s, _ := src.GetOrCreate(1)
+	s.SetState("checkout")
+	s.Set("items", 3)
+	s, _ = src.GetOrCreate(2)
+	s.Set("name", "john")
+
+	var buf bytes.Buffer
+	if err := session.Export(t.Context(), src.(session.Enumerable[int64]), &buf, nil); err != nil {
+		t.Fatalf(expectsNoError, err)
+	}
+
+	dst := tgbotapp.NewStoreManager(tgbotapp.NewMemoryStore(), nil)
+
+	// Act
+	n, err := session.Import(t.Context(), dst, &buf, nil)
+
+	// Assert
+	if err != nil {
+		t.Fatalf(expectsNoError, err)
+	}
+	if n != 2 {
+		t.Errorf("Expected %d imported sessions, found %d", 2, n)
+	}
+
+	snap, found, err := session.Inspect(t.Context(), dst, 1)
+	if err != nil || !found {
+		t.Fatalf("Expected session 1 to be found. Got error: %v", err)
+	}
+	if snap.State != "checkout" {
+		t.Errorf("Expected state %q, found %q", "checkout", snap.State)
+	}
+	if v, ok := snap.Data["items"].(int); !ok || v != 3 {
+		t.Errorf("Expected int 3, found %#v", snap.Data["items"])
+	}
+}
+
+func TestInspectShouldLoadSessionWithoutEnumerating(t *testing.T) {
+	// Arrange
+	store := struct{ session.Store[int64] }{tgbotapp.NewMemoryStore()}
+	mgr := tgbotapp.NewStoreManager(store, nil)
+	s, _ := mgr.GetOrCreate(1)
+	s.Set("name", "john")
+	mgr.Set(1, s)
+
+	// Act
+	snap, found, err := session.Inspect(t.Context(), mgr, 1)
+	_, missing, missingErr := session.Inspect(t.Context(), mgr, 2)
+
+	// Assert
+	if err != nil || !found {
+		t.Fatalf("Expected session 1 to be found. Got error: %v", err)
+	}
+	if snap.Data["name"] != "john" {
+		t.Errorf("Expected name %q, found %#v", "john", snap.Data["name"])
+	}
+	if missingErr != nil || missing {
+		t.Errorf("Expected session 2 not to be found. Got error: %v", missingErr)
+	}
+}
+
+func TestMigrateShouldCopyAllSessions(t *testing.T) {
+	// Arrange
+	src := tgbotapp.NewDefaultInMemoryManager()
+	for i := range int64(5) {
+		s, _ := src.GetOrCreate(i)
+		s.Set("n", int(i))
+	}
+	dst := tgbotapp.NewStoreManager(tgbotapp.NewMemoryStore(), nil)
+
+	// Act
+	n, err := session.Migrate(t.Context(), src.(session.Enumerable[int64]), dst)
+
+	// Assert
+	if err != nil {
+		t.Fatalf(expectsNoError, err)
+	}
+	if n != 5 {
+		t.Errorf("Expected %d migrated sessions, found %d", 5, n)
+	}
+
+	s, _ := dst.GetOrCreate(4)
+	if v, _ := session.Get[int](s, "n"); v != 4 {
+		t.Errorf("Expected %d, found %d", 4, v)
+	}
+}
@@ -0,0 +1,498 @@
+package tgbotapp
+
+import (
+	"html"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nexoratech2025/go-telegram-bot-app/format"
+)
+
+// Maximum length of a message text accepted by Telegram, in UTF-16 code
+// units of the text left once markup is parsed.
+const MaxMessageLength = 4096
+
+type tokenKind int
+
+const (
+	tokenText tokenKind = iota
+	tokenOpen
+	tokenClose
+)
+
+// Smallest unit the splitter works with: a character, or markup that must
+// not be cut such as a tag, an escape sequence or a link.
+type textToken struct {
+	text string
+	kind tokenKind
+	// Markup identifier used to match open and close tokens.
+	name string
+	// Text that closes an open token.
+	closer string
+	// Length of the visible text in UTF-16 code units; 0 for markup.
+	width int
+}
+
+// Return a token of text that reads as visible once parsed.
+func visibleToken(text, visible string) textToken {
+	return textToken{text: text, width: utf16Len(visible)}
+}
+
+func charToken(r rune) textToken {
+	return textToken{text: string(r), width: utf16.RuneLen(r)}
+}
+
+// Length of s in UTF-16 code units, as Telegram counts it.
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+// Open formatting at some point of the text.
+type openEntity struct {
+	name   string
+	opener string
+	closer string
+}
+
+// Break opportunity after a token, ranked paragraph > line > word.
+type breakPoint struct {
+	index int
+	rank  int
+	open  []openEntity
+}
+
+// SplitMessage splits text into chunks of at most limit UTF-16 code units of
+// visible text, breaking at paragraph, line or word boundaries where
+// possible. Tags, escape sequences and links of the given parse mode are
+// never cut, and formatting that is open at a break is closed at the end of
+// the chunk and re-opened at the start of the next one.
+func SplitMessage(text, parseMode string, limit int) []string {
+	if limit <= 0 {
+		limit = MaxMessageLength
+	}
+
+	// Markup only adds to the length.
+	if utf16Len(text) <= limit {
+		return []string{text}
+	}
+
+	var tokens []textToken
+	switch parseMode {
+	case ParseModeHTML:
+		tokens = tokenizeHTML(text)
+	case ParseModeMarkdownV2:
+		tokens = tokenizeMarkdown(text, true)
+	case ParseModeMarkdown:
+		tokens = tokenizeMarkdown(text, false)
+	default:
+		tokens = tokenizePlain(text)
+	}
+
+	return splitTokens(tokens, limit)
+}
+
+// SplitEntities splits a text with message entities like SplitMessage. The
+// entities are moved to the chunks they belong to, and entities spanning a
+// break are cut in two.
+func SplitEntities(text string, entities []tgbotapi.MessageEntity, limit int) []format.Message {
+	if limit <= 0 {
+		limit = MaxMessageLength
+	}
+
+	if utf16Len(text) <= limit {
+		return []format.Message{{Text: text, Entities: entities}}
+	}
+
+	runes := []rune(text)
+
+	// offsets[i] is the UTF-16 offset of runes[i].
+	offsets := make([]int, len(runes)+1)
+	for i, r := range runes {
+		offsets[i+1] = offsets[i] + utf16.RuneLen(r)
+	}
+
+	var chunks []format.Message
+	start := 0
+
+	for start < len(runes) {
+		for start < len(runes) && isSpace(string(runes[start])) {
+			start++
+		}
+		if start >= len(runes) {
+			break
+		}
+
+		best, bestRank := -1, 0
+		end := start
+		for ; end < len(runes); end++ {
+			if offsets[end+1]-offsets[start] > limit && end > start {
+				break
+			}
+			if rank := runeBreakRank(runes, end); rank > 0 && rank >= bestRank {
+				best, bestRank = end+1, rank
+			}
+		}
+
+		if end < len(runes) && best > start {
+			end = best
+		}
+
+		stop := end
+		for stop > start && isSpace(string(runes[stop-1])) {
+			stop--
+		}
+
+		chunks = append(chunks, format.Message{
+			Text:     string(runes[start:stop]),
+			Entities: clipEntities(entities, offsets[start], offsets[stop]),
+		})
+
+		start = end
+	}
+
+	return chunks
+}
+
+// Same as breakRank for the break after runes[i].
+func runeBreakRank(runes []rune, i int) int {
+	switch runes[i] {
+	case '\n':
+		if i > 0 && runes[i-1] == '\n' {
+			return 3
+		}
+		return 2
+	case ' ', '\t':
+		return 1
+	}
+	return 0
+}
+
+// Return the parts of entities between the UTF-16 offsets from and to,
+// relative to from.
+func clipEntities(entities []tgbotapi.MessageEntity, from, to int) []tgbotapi.MessageEntity {
+	var clipped []tgbotapi.MessageEntity
+	for _, e := range entities {
+		start, end := max(e.Offset, from), min(e.Offset+e.Length, to)
+		if end <= start {
+			continue
+		}
+		e.Offset, e.Length = start-from, end-start
+		clipped = append(clipped, e)
+	}
+	return clipped
+}
+
+func splitTokens(tokens []textToken, limit int) []string {
+	var chunks []string
+
+	var open []openEntity
+	start := 0
+
+	for start < len(tokens) {
+		// Skip whitespace left over from the previous break.
+		for start < len(tokens) && tokens[start].kind == tokenText && isSpace(tokens[start].text) {
+			start++
+		}
+		if start >= len(tokens) {
+			break
+		}
+
+		prefix := openers(open)
+		width := 0
+		stack := append([]openEntity(nil), open...)
+
+		var best *breakPoint
+		end := start
+		for ; end < len(tokens); end++ {
+			tok := tokens[end]
+			next := applyToken(stack, tok)
+
+			w := width + tok.width
+			if w > limit && end > start {
+				break
+			}
+
+			width, stack = w, next
+
+			if rank := breakRank(tokens, end); rank > 0 && (best == nil || rank >= best.rank) {
+				best = &breakPoint{index: end + 1, rank: rank, open: append([]openEntity(nil), stack...)}
+			}
+		}
+
+		if end < len(tokens) && best != nil && best.index > start {
+			end, stack = best.index, best.open
+		}
+
+		var b strings.Builder
+		b.WriteString(prefix)
+		for _, tok := range tokens[start:end] {
+			b.WriteString(tok.text)
+		}
+
+		chunk := strings.TrimRight(b.String(), " \n")
+		if end < len(tokens) {
+			chunk += closers(stack)
+		}
+		chunks = append(chunks, chunk)
+
+		start, open = end, stack
+	}
+
+	return chunks
+}
+
+// Rank the break after tokens[i]: 3 for a paragraph, 2 for a line, 1 for a word.
+func breakRank(tokens []textToken, i int) int {
+	tok := tokens[i]
+	if tok.kind != tokenText {
+		return 0
+	}
+
+	switch tok.text {
+	case "\n":
+		if i > 0 && tokens[i-1].text == "\n" {
+			return 3
+		}
+		return 2
+	case " ", "\t":
+		return 1
+	}
+	return 0
+}
+
+func applyToken(stack []openEntity, tok textToken) []openEntity {
+	switch tok.kind {
+	case tokenOpen:
+		next := append([]openEntity(nil), stack...)
+		return append(next, openEntity{name: tok.name, opener: tok.text, closer: tok.closer})
+	case tokenClose:
+		for i := len(stack) - 1; i >= 0; i-- {
+			if stack[i].name == tok.name {
+				return append([]openEntity(nil), stack[:i]...)
+			}
+		}
+	}
+	return stack
+}
+
+func openers(stack []openEntity) string {
+	var b strings.Builder
+	for _, e := range stack {
+		b.WriteString(e.opener)
+	}
+	return b.String()
+}
+
+func closers(stack []openEntity) string {
+	var b strings.Builder
+	for i := len(stack) - 1; i >= 0; i-- {
+		b.WriteString(stack[i].closer)
+	}
+	return b.String()
+}
+
+func isSpace(s string) bool {
+	return s == " " || s == "\n" || s == "\t"
+}
+
+func tokenizePlain(text string) []textToken {
+	tokens := make([]textToken, 0, len(text))
+	for _, r := range text {
+		tokens = append(tokens, charToken(r))
+	}
+	return tokens
+}
+
+func tokenizeHTML(text string) []textToken {
+	var tokens []textToken
+
+	for i := 0; i < len(text); {
+		switch text[i] {
+		case '<':
+			end := strings.IndexByte(text[i:], '>')
+			if end < 0 {
+				break
+			}
+			tag := text[i : i+end+1]
+			tokens = append(tokens, htmlTagToken(tag))
+			i += end + 1
+			continue
+
+		case '&':
+			end := strings.IndexByte(text[i:], ';')
+			if end > 0 && end <= 10 {
+				entity := text[i : i+end+1]
+				tokens = append(tokens, visibleToken(entity, html.UnescapeString(entity)))
+				i += end + 1
+				continue
+			}
+		}
+
+		r, size := utf8.DecodeRuneInString(text[i:])
+		tokens = append(tokens, charToken(r))
+		i += size
+	}
+
+	return tokens
+}
+
+func htmlTagToken(tag string) textToken {
+	inner := strings.Trim(tag, "<>/ ")
+	name := inner
+	if i := strings.IndexAny(inner, " \t\n"); i >= 0 {
+		name = inner[:i]
+	}
+	name = strings.ToLower(name)
+
+	if strings.HasPrefix(tag, "</") {
+		return textToken{text: tag, kind: tokenClose, name: name}
+	}
+
+	return textToken{text: tag, kind: tokenOpen, name: name, closer: "</" + name + ">"}
+}
+
+// Tokenize MarkdownV2 (v2 true) or legacy Markdown.
+func tokenizeMarkdown(text string, v2 bool) []textToken {
+	var tokens []textToken
+
+	markers := []string{"*", "_", "`"}
+	if v2 {
+		markers = []string{"||", "__", "*", "_", "~", "`"}
+	}
+
+	open := map[string]bool{}
+	code := false
+
+	toggle := func(marker, opener string) {
+		if open[marker] {
+			tokens = append(tokens, textToken{text: marker, kind: tokenClose, name: marker})
+		} else {
+			tokens = append(tokens, textToken{text: opener, kind: tokenOpen, name: marker, closer: marker})
+		}
+		open[marker] = !open[marker]
+	}
+
+	for i := 0; i < len(text); {
+		rest := text[i:]
+
+		// Escaped character.
+		if rest[0] == '\\' && len(rest) > 1 {
+			_, size := utf8.DecodeRuneInString(rest[1:])
+			tokens = append(tokens, visibleToken(rest[:1+size], rest[1:1+size]))
+			i += 1 + size
+			continue
+		}
+
+		// Pre block, re-opened with its language line.
+		if strings.HasPrefix(rest, "```") {
+			opener := "```"
+			if !open["```"] {
+				if nl := strings.IndexByte(rest, '\n'); nl >= 0 {
+					opener = rest[:nl+1]
+				}
+			}
+			toggle("```", opener)
+			code = open["```"]
+			i += len(opener)
+			continue
+		}
+
+		if code {
+			r, size := utf8.DecodeRuneInString(rest)
+			tokens = append(tokens, charToken(r))
+			i += size
+			continue
+		}
+
+		if rest[0] == '`' {
+			toggle("`", "`")
+			i++
+			// Inline code that just opened runs until the closing backtick.
+			if end := strings.IndexByte(text[i:], '`'); open["`"] && end >= 0 {
+				for _, r := range text[i : i+end] {
+					tokens = append(tokens, charToken(r))
+				}
+				i += end
+			}
+			continue
+		}
+
+		// Links and custom emoji are kept whole.
+		if rest[0] == '[' || (v2 && strings.HasPrefix(rest, "![")) {
+			if end := markdownLinkEnd(rest); end > 0 {
+				tokens = append(tokens, visibleToken(rest[:end], markdownLinkText(rest[:end])))
+				i += end
+				continue
+			}
+		}
+
+		matched := false
+		for _, m := range markers {
+			if m != "`" && strings.HasPrefix(rest, m) {
+				toggle(m, m)
+				i += len(m)
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(rest)
+		tokens = append(tokens, charToken(r))
+		i += size
+	}
+
+	return tokens
+}
+
+// Return the visible text of link, without its escapes.
+func markdownLinkText(link string) string {
+	link = strings.TrimPrefix(link, "!")
+	var b strings.Builder
+	for i := 1; i < len(link) && link[i] != ']'; i++ {
+		if link[i] == '\\' && i+1 < len(link) {
+			i++
+		}
+		b.WriteByte(link[i])
+	}
+	return b.String()
+}
+
+// Return the length of the link at the start of s, or 0 if there is none.
+func markdownLinkEnd(s string) int {
+	close := -1
+	for i := 1; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == ']' {
+			close = i
+			break
+		}
+	}
+
+	if close < 0 || close+1 >= len(s) || s[close+1] != '(' {
+		return 0
+	}
+
+	for i := close + 2; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == ')' {
+			return i + 1
+		}
+	}
+
+	return 0
+}
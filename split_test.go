@@ -0,0 +1,209 @@
+package tgbotapp_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	tgbotapp "github.com/nexoratech2025/go-telegram-bot-app"
+)
+
+func TestSplitMessageShouldNotSplitShortText(t *testing.T) {
+	// Act
+	chunks := tgbotapp.SplitMessage("hello", "", 10)
+
+	// Assert
+	if len(chunks) != 1 || chunks[0] != "hello" {
+		t.Errorf("Expected text to be unchanged, found %q", chunks)
+	}
+}
+
+func TestSplitMessageShouldPreferParagraphs(t *testing.T) {
+	// Arrange
+	text := "first paragraph\n\nsecond line\nthird words here"
+
+	// Act
+	chunks := tgbotapp.SplitMessage(text, "", 30)
+
+	// Assert
+	if len(chunks) != 2 || chunks[0] != "first paragraph" || chunks[1] != "second line\nthird words here" {
+		t.Errorf("Expected split at the paragraph, found %q", chunks)
+	}
+}
+
+func TestSplitMessageShouldSplitAtWords(t *testing.T) {
+	// Arrange
+	text := "one two three four five six"
+
+	// Act
+	chunks := tgbotapp.SplitMessage(text, "", 10)
+
+	// Assert
+	for _, c := range chunks {
+		if utf8.RuneCountInString(c) > 10 {
+			t.Errorf("Expected chunk of at most 10 characters, found %q", c)
+		}
+		if strings.HasPrefix(c, " ") || strings.HasSuffix(c, " ") {
+			t.Errorf("Expected chunk without surrounding spaces, found %q", c)
+		}
+	}
+	if strings.Join(chunks, " ") != text {
+		t.Errorf("Expected chunks to make up the text, found %q", chunks)
+	}
+}
+
+func TestSplitMessageShouldCutLongWords(t *testing.T) {
+	// Act
+	chunks := tgbotapp.SplitMessage(strings.Repeat("ж", 25), "", 10)
+
+	// Assert
+	if len(chunks) != 3 || utf8.RuneCountInString(chunks[0]) != 10 {
+		t.Errorf("Expected word to be cut at the limit, found %q", chunks)
+	}
+}
+
+func TestSplitMessageShouldReopenHTMLTags(t *testing.T) {
+	// Arrange
+	text := `<b>bold <a href="https://example.com">link text here</a> end</b>`
+
+	// Act
+	chunks := tgbotapp.SplitMessage(text, tgbotapp.ParseModeHTML, 15)
+
+	// Assert
+	if len(chunks) < 2 {
+		t.Fatalf("Expected text to be split, found %q", chunks)
+	}
+	for _, c := range chunks {
+		if strings.Count(c, "<b>") != strings.Count(c, "</b>") || strings.Count(c, "<a ") != strings.Count(c, "</a>") {
+			t.Errorf("Expected balanced tags, found %q", c)
+		}
+	}
+	if !strings.HasPrefix(chunks[1], `<b><a href="https://example.com">`) {
+		t.Errorf("Expected formatting to be re-opened, found %q", chunks[1])
+	}
+}
+
+func TestSplitMessageShouldNotCutHTMLEntities(t *testing.T) {
+	// Arrange
+	text := "aaaaaaa&amp;bbbbbbb"
+
+	// Act
+	chunks := tgbotapp.SplitMessage(text, tgbotapp.ParseModeHTML, 7)
+
+	// Assert
+	if chunks[0] != "aaaaaaa" || !strings.HasPrefix(chunks[1], "&amp;") {
+		t.Errorf("Expected entity to be kept whole, found %q", chunks)
+	}
+}
+
+func TestSplitMessageShouldReopenMarkdownV2Entities(t *testing.T) {
+	// Arrange
+	text := "*bold _italic words continue here_ done*"
+
+	// Act
+	chunks := tgbotapp.SplitMessage(text, tgbotapp.ParseModeMarkdownV2, 25)
+
+	// Assert
+	if len(chunks) < 2 {
+		t.Fatalf("Expected text to be split, found %q", chunks)
+	}
+	if !strings.HasSuffix(chunks[0], "_*") {
+		t.Errorf("Expected entities to be closed, found %q", chunks[0])
+	}
+	if !strings.HasPrefix(chunks[1], "*_") {
+		t.Errorf("Expected entities to be re-opened, found %q", chunks[1])
+	}
+}
+
+func TestSplitMessageShouldReopenMarkdownV2EntitiesBetweenInlineCode(t *testing.T) {
+	// Arrange
+	text := "`a` *bold bold bold bold bold bold* `c`"
+
+	// Act
+	chunks := tgbotapp.SplitMessage(text, tgbotapp.ParseModeMarkdownV2, 20)
+
+	// Assert
+	if len(chunks) < 2 {
+		t.Fatalf("Expected text to be split, found %q", chunks)
+	}
+	if !strings.HasSuffix(chunks[0], "*") {
+		t.Errorf("Expected bold to be closed, found %q", chunks[0])
+	}
+	if !strings.HasPrefix(chunks[1], "*") {
+		t.Errorf("Expected bold to be re-opened, found %q", chunks[1])
+	}
+}
+
+func TestSplitMessageShouldKeepMarkdownV2EscapesAndLinks(t *testing.T) {
+	// Arrange
+	text := `abc\. [link text](https://example.com/path) end`
+
+	// Act
+	chunks := tgbotapp.SplitMessage(text, tgbotapp.ParseModeMarkdownV2, 12)
+
+	// Assert
+	found := false
+	for _, c := range chunks {
+		if strings.HasSuffix(c, `\`) {
+			t.Errorf("Expected escape to be kept whole, found %q", c)
+		}
+		found = found || strings.Contains(c, "[link text](https://example.com/path)")
+	}
+	if !found {
+		t.Errorf("Expected link to be kept whole, found %q", chunks)
+	}
+}
+
+func TestSplitMessageShouldReopenPreBlocks(t *testing.T) {
+	// Arrange
+	text := "```go\nline one\nline two\nline three\n```"
+
+	// Act
+	chunks := tgbotapp.SplitMessage(text, tgbotapp.ParseModeMarkdownV2, 25)
+
+	// Assert
+	if len(chunks) < 2 {
+		t.Fatalf("Expected text to be split, found %q", chunks)
+	}
+	for _, c := range chunks {
+		if !strings.HasPrefix(c, "```go\n") || !strings.HasSuffix(c, "```") {
+			t.Errorf("Expected every chunk to be a complete pre block, found %q", c)
+		}
+	}
+}
+
+func TestSplitMessageShouldCountVisibleUTF16Units(t *testing.T) {
+	// Arrange
+	text := "<b>" + strings.Repeat("😀", 3) + "</b> " + strings.Repeat("😀", 3)
+
+	// Act
+	chunks := tgbotapp.SplitMessage(text, tgbotapp.ParseModeHTML, 7)
+
+	// Assert
+	expected := []string{"<b>😀😀😀</b>", "😀😀😀"}
+	if !slices.Equal(chunks, expected) {
+		t.Errorf("Expected %q, found %q", expected, chunks)
+	}
+}
+
+func TestSplitEntitiesShouldMoveEntitiesToTheirChunks(t *testing.T) {
+	// Arrange
+	text := "😀 bold words here"
+	entities := []tgbotapi.MessageEntity{{Type: "bold", Offset: 3, Length: 15}}
+
+	// Act
+	chunks := tgbotapp.SplitEntities(text, entities, 10)
+
+	// Assert
+	if len(chunks) != 2 || chunks[0].Text != "😀 bold" || chunks[1].Text != "words here" {
+		t.Fatalf("Expected split at the word, found %+v", chunks)
+	}
+	if e := chunks[0].Entities; len(e) != 1 || e[0].Offset != 3 || e[0].Length != 4 {
+		t.Errorf("Expected bold entity at 3 of length 4, found %+v", e)
+	}
+	if e := chunks[1].Entities; len(e) != 1 || e[0].Offset != 0 || e[0].Length != 10 {
+		t.Errorf("Expected bold entity at 0 of length 10, found %+v", e)
+	}
+}
@@ -0,0 +1,72 @@
+package tgbotapp_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	tgbotapp "github.com/nexoratech2025/go-telegram-bot-app"
+	"github.com/nexoratech2025/go-telegram-bot-app/testutil"
+)
+
+func TestStartShouldHandleUpdatesFromBotAPI(t *testing.T) {
+	// Arrange
+	f := testutil.NewFakeBotAPI(t)
+	app := tgbotapp.Default(f.NewBotAPI(t))
+	app.RegisterCommand("hello", "Say hello", func(ctx *tgbotapp.BotContext) {
+		h := tgbotapp.NewHandlerContext(ctx, "hello")
+		h.SendMessage("Hello " + h.GetCommandArguments())
+	})
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+	go func() {
+		done <- app.Start(ctx)
+	}()
+
+	// Act
+	f.PushMessage(7, "/hello Bob")
+	call := f.WaitForCall(t, "sendMessage", 1, 5*time.Second)
+	cancel()
+
+	// Assert
+	if call.Params.Get("chat_id") != "7" || call.Params.Get("text") != "Hello Bob" {
+		t.Errorf("Expected greeting to chat 7, found %v", call.Params)
+	}
+	if len(f.CallsTo("setMyCommands")) == 0 {
+		t.Error("Expected command list to be set")
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Expected application to stop cleanly, found %v", err)
+	}
+}
+
+func TestStartShouldRunRateLimitSchedulerWhileStarted(t *testing.T) {
+	// Arrange
+	f := testutil.NewFakeBotAPI(t)
+	app := tgbotapp.Default(f.NewBotAPI(t), tgbotapp.WithRateLimits(tgbotapp.RateLimits{}))
+	created := app.Outbound
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+	go func() {
+		done <- app.Start(ctx)
+	}()
+
+	// Act
+	cancel()
+	<-done
+
+	// Assert
+	if created != nil {
+		t.Error("Expected scheduler not to be started before Start")
+	}
+	if app.Outbound == nil {
+		t.Fatal("Expected scheduler to be started by Start")
+	}
+	err := app.Outbound.Do(t.Context(), 1, func() error { return nil })
+	if !errors.Is(err, tgbotapp.ErrSchedulerClosed) {
+		t.Errorf("Expected scheduler to be closed after Start returned, found %v", err)
+	}
+}
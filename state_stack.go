@@ -0,0 +1,194 @@
+package tgbotapp
+
+import (
+	"maps"
+
+	"github.com/nexoratech2025/go-telegram-bot-app/session"
+)
+
+const (
+	// Name of the prompt run when the session is back to the empty state, e.g.
+	// app.RegisterPrompt(RootPrompt, showMenu).
+	RootPrompt = "_root"
+)
+
+// State stack implementation of DefaultSession.
+
+func (s *DefaultSession) top() *session.Frame {
+	return &s.stack[len(s.stack)-1]
+}
+
+// Apply f to the stack and notify state change listeners if the current
+// state changed.
+func (s *DefaultSession) changeStack(f func() bool) {
+	s.mu.Lock()
+	old := s.top().State
+	if !f() {
+		s.mu.Unlock()
+		return
+	}
+	s.dirty = true
+	state := s.top().State
+	listeners := listenersOf(s, s.onStateChange)
+	s.mu.Unlock()
+
+	if old == state {
+		return
+	}
+
+	for _, l := range listeners {
+		l(old, state)
+	}
+}
+
+// Push implements session.StateStack.
+func (s *DefaultSession) Push(state string) {
+	s.changeStack(func() bool {
+		s.stack = append(s.stack, session.Frame{State: state})
+		return true
+	})
+}
+
+// Pop implements session.StateStack.
+func (s *DefaultSession) Pop() (state string, ok bool) {
+	s.changeStack(func() bool {
+		if len(s.stack) < 2 {
+			return false
+		}
+		s.stack = s.stack[:len(s.stack)-1]
+		state, ok = s.top().State, true
+		return true
+	})
+	return
+}
+
+// Replace implements session.StateStack.
+func (s *DefaultSession) Replace(state string) {
+	s.changeStack(func() bool {
+		if s.top().State == state {
+			return false
+		}
+		s.top().State = state
+		return true
+	})
+}
+
+// Peek implements session.StateStack.
+func (s *DefaultSession) Peek() string {
+	return s.CurrentState()
+}
+
+// Reset implements session.StateStack.
+func (s *DefaultSession) Reset() {
+	s.changeStack(func() bool {
+		if len(s.stack) == 1 && s.stack[0].State == "" && len(s.stack[0].Data) == 0 {
+			return false
+		}
+		s.stack = []session.Frame{{}}
+		return true
+	})
+}
+
+// Depth implements session.StateStack.
+func (s *DefaultSession) Depth() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.stack)
+}
+
+// FrameGet implements session.StateStack.
+func (s *DefaultSession) FrameGet(key string) (value any, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, ok = s.top().Data[key]
+	return
+}
+
+// FrameSet implements session.StateStack.
+func (s *DefaultSession) FrameSet(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	top := s.top()
+	if top.Data == nil {
+		top.Data = make(map[string]any)
+	}
+	top.Data[key] = value
+	s.dirty = true
+}
+
+// Frames implements session.StateStack.
+func (s *DefaultSession) Frames() []session.Frame {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	frames := make([]session.Frame, len(s.stack))
+	for i, f := range s.stack {
+		frames[i] = session.Frame{State: f.State, Data: maps.Clone(f.Data)}
+	}
+	return frames
+}
+
+// SetFrames implements session.StateStack.
+func (s *DefaultSession) SetFrames(frames []session.Frame) {
+	s.changeStack(func() bool {
+		s.stack = make([]session.Frame, 0, max(len(frames), 1))
+		for _, f := range frames {
+			s.stack = append(s.stack, session.Frame{State: f.State, Data: maps.Clone(f.Data)})
+		}
+		if len(s.stack) == 0 {
+			s.stack = append(s.stack, session.Frame{})
+		}
+		return true
+	})
+}
+
+// Pops the current state off the session's state stack and runs the prompt
+// registered for the state below it. Register it as a command or callback,
+// e.g. app.RegisterCommand("back", "Go back", BackHandler).
+func BackHandler(ctx *BotContext) {
+	st, ok := ctx.Session.(session.StateStack)
+	if !ok {
+		ctx.Logger().WarnContext(ctx.Ctx, "Session does not support state stack.")
+		return
+	}
+
+	state, ok := st.Pop()
+	if !ok {
+		state = st.Peek()
+	}
+
+	runPrompt(ctx, state)
+}
+
+// Resets the session's state stack and runs the prompt registered as
+// RootPrompt, if any.
+func CancelHandler(ctx *BotContext) {
+	st, ok := ctx.Session.(session.StateStack)
+	if !ok {
+		if ctx.Session != nil {
+			ctx.Session.SetState("")
+		}
+	} else {
+		st.Reset()
+	}
+
+	runPrompt(ctx, "")
+}
+
+// Run the prompt of state, or RootPrompt for the empty state.
+func runPrompt(ctx *BotContext, state string) {
+	if ctx.app == nil || ctx.app.Router == nil {
+		return
+	}
+	if state == "" {
+		state = RootPrompt
+	}
+
+	h, ok := ctx.app.Router.GetHandler(state, PromptHandler)
+	if !ok {
+		ctx.Logger().DebugContext(ctx.Ctx, "No prompt registered for state.", "state", state)
+		return
+	}
+
+	h.Func(ctx)
+}
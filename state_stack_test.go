@@ -0,0 +1,144 @@
+package tgbotapp_test
+
+import (
+	"log/slog"
+	"testing"
+
+	tgbotapp "github.com/nexoratech2025/go-telegram-bot-app"
+	"github.com/nexoratech2025/go-telegram-bot-app/session"
+)
+
+func TestStateStackShouldPushAndPop(t *testing.T) {
+	// Arrange
+	s := tgbotapp.NewDefaultSession().(session.StateStack)
+	s.Replace("checkout")
+
+	// Act
+	s.Push("add_address")
+	s.FrameSet("street", "Main St")
+
+	// Assert
+	if s.Peek() != "add_address" || s.Depth() != 2 {
+		t.Errorf("Expected state %q at depth %d, found %q at depth %d", "add_address", 2, s.Peek(), s.Depth())
+	}
+
+	state, ok := s.Pop()
+	if !ok || state != "checkout" {
+		t.Errorf("Expected to pop back to %q, found %q (ok=%v)", "checkout", state, ok)
+	}
+
+	if _, ok := s.FrameGet("street"); ok {
+		t.Errorf("Expected frame data to be discarded with its frame")
+	}
+
+	if _, ok := s.Pop(); ok {
+		t.Errorf("Expected bottom frame not to be popped")
+	}
+}
+
+func TestStateStackShouldRoundTripThroughStore(t *testing.T) {
+	// Arrange
+	mgr := tgbotapp.NewStoreManager(tgbotapp.NewMemoryStore(), nil)
+	const chatID int64 = 123
+	s, _ := mgr.GetOrCreate(chatID)
+	st := s.(session.StateStack)
+	st.Replace("checkout")
+	st.Push("add_address")
+	st.FrameSet("step", 2)
+
+	// Act
+	err := mgr.Set(chatID, s)
+
+	// Assert
+	if err != nil {
+		t.Fatalf(expectsNoError, err)
+	}
+
+	s, _ = mgr.GetOrCreate(chatID)
+	st = s.(session.StateStack)
+	if st.Depth() != 2 || s.CurrentState() != "add_address" {
+		t.Errorf("Expected state %q at depth %d, found %q at depth %d", "add_address", 2, s.CurrentState(), st.Depth())
+	}
+	if v, _ := st.FrameGet("step"); v != 2 {
+		t.Errorf("Expected frame data %d, found %#v", 2, v)
+	}
+}
+
+func TestBackHandlerShouldPopAndRunPreviousPrompt(t *testing.T) {
+	// Arrange
+	app := tgbotapp.New(nil, func(a *tgbotapp.Application) {
+		a.Logger = slog.Default()
+		a.Router = tgbotapp.NewRouteTable()
+	})
+
+	prompted := ""
+	app.RegisterPrompt("checkout", func(ctx *tgbotapp.BotContext) {
+		prompted = ctx.Session.CurrentState()
+	})
+
+	ctx := tgbotapp.NewBotContext(t.Context(), app, newChatUpdate(123, "/back"))
+	ctx.Session = tgbotapp.NewDefaultSession()
+	h := tgbotapp.NewHandlerContext(ctx, "test")
+	h.SetState("checkout")
+	h.PushState("add_address")
+
+	// Act
+	tgbotapp.BackHandler(ctx)
+
+	// Assert
+	if prompted != "checkout" {
+		t.Errorf("Expected prompt for %q to run, found %q", "checkout", prompted)
+	}
+}
+
+func TestCancelHandlerShouldResetAndRunRootPrompt(t *testing.T) {
+	// Arrange
+	app := tgbotapp.New(nil, func(a *tgbotapp.Application) {
+		a.Logger = slog.Default()
+		a.Router = tgbotapp.NewRouteTable()
+	})
+
+	prompted := false
+	if err := app.RegisterPrompt(tgbotapp.RootPrompt, func(ctx *tgbotapp.BotContext) {
+		prompted = true
+	}); err != nil {
+		t.Fatalf(expectsNoError, err)
+	}
+
+	ctx := tgbotapp.NewBotContext(t.Context(), app, newChatUpdate(123, "/cancel"))
+	ctx.Session = tgbotapp.NewDefaultSession()
+	h := tgbotapp.NewHandlerContext(ctx, "test")
+	h.SetState("checkout")
+	h.PushState("add_address")
+
+	// Act
+	tgbotapp.CancelHandler(ctx)
+
+	// Assert
+	if !prompted {
+		t.Error("Expected root prompt to run")
+	}
+	if ctx.Session.CurrentState() != "" {
+		t.Errorf("Expected empty state, found %q", ctx.Session.CurrentState())
+	}
+}
+
+type framelessSession struct {
+	*tgbotapp.DefaultSession
+}
+
+func (framelessSession) Frames() []session.Frame { return nil }
+
+func TestTakeSnapshotShouldAcceptStackWithoutFrames(t *testing.T) {
+	// Arrange
+	s := framelessSession{tgbotapp.NewDefaultSession().(*tgbotapp.DefaultSession)}
+	s.SetState("idle")
+
+	// Act
+	snap := session.TakeSnapshot(s)
+
+	// Assert
+	if snap.State != "idle" || snap.Stack != nil {
+		t.Errorf("Expected state %q without stack, found %q with %v", "idle", snap.State, snap.Stack)
+	}
+}
@@ -0,0 +1,422 @@
+package tgbotapp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nexoratech2025/go-telegram-bot-app/format"
+)
+
+var (
+	ErrTemplateNotFound = errors.New("Message template not found.")
+	ErrNoTemplates      = errors.New("Application has no message templates.")
+)
+
+// Extension of template files. It is removed from file names to get the
+// template names.
+const TemplateExt = ".tmpl"
+
+// Templates is a registry of named message templates using text/template.
+//
+// A template may start with a metadata block:
+//
+//	---
+//	parse_mode: HTML
+//	---
+//	Hello, {{bold .Name}}!
+//
+// Supported parse modes are HTML and MarkdownV2; without one the text is
+// sent as is. Literal template text must already be valid markup for the
+// parse mode. Every value printed by an action is escaped for the parse mode,
+// like html/template does. The formatting helpers (bold, italic, underline,
+// strikethrough, spoiler, code, pre, link, mention) escape their arguments
+// and print markup; values of type format.Node are rendered, so format.Raw is
+// the way to print markup that is not escaped.
+//
+// Templates whose file name starts with "_" are partials. They are not sent
+// on their own, but the templates they define can be used by all templates
+// added after them.
+type Templates struct {
+	funcs    template.FuncMap
+	partials *template.Template
+	set      map[string]*messageTemplate
+	// Parse trees whose actions already escape their output. Trees of
+	// partials are shared by all templates.
+	escaped map[*parse.Tree]bool
+}
+
+type messageTemplate struct {
+	tmpl      *template.Template
+	parseMode string
+}
+
+func NewTemplates() *Templates {
+	t := &Templates{
+		funcs:   make(template.FuncMap),
+		set:     make(map[string]*messageTemplate),
+		escaped: make(map[*parse.Tree]bool),
+	}
+	t.partials = template.New("").Funcs(helperFuncs("")).Funcs(t.funcs)
+	return t
+}
+
+// Funcs adds functions available to templates added afterwards. Functions
+// with the name of a helper replace it.
+func (t *Templates) Funcs(funcs template.FuncMap) *Templates {
+	for name, fn := range funcs {
+		t.funcs[name] = fn
+	}
+	t.partials.Funcs(funcs)
+	return t
+}
+
+// ParseDir adds the template files in dir matching patterns, *.tmpl by
+// default.
+func (t *Templates) ParseDir(dir string, patterns ...string) error {
+	return t.ParseFS(os.DirFS(dir), patterns...)
+}
+
+// ParseFS adds the template files in fsys matching patterns, *.tmpl by
+// default. Templates are named after their path without extension, such as
+// "welcome" for welcome.tmpl or "admin/stats" for admin/stats.tmpl. Partials
+// are added first.
+func (t *Templates) ParseFS(fsys fs.FS, patterns ...string) error {
+	if len(patterns) == 0 {
+		patterns = []string{"*" + TemplateExt}
+	}
+
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return err
+		}
+		files = append(files, matches...)
+	}
+
+	if len(files) == 0 {
+		return fmt.Errorf("template: no files match %q", patterns)
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return isPartial(files[i]) && !isPartial(files[j])
+	})
+
+	for _, file := range files {
+		b, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+
+		if err = t.Add(strings.TrimSuffix(file, TemplateExt), string(b)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Add parses text as the template name. Names whose last element starts
+// with "_" are partials.
+func (t *Templates) Add(name, text string) error {
+	parseMode, body, err := parseTemplateMeta(text)
+	if err != nil {
+		return fmt.Errorf("template %s: %w", name, err)
+	}
+
+	if isPartial(name) {
+		if parseMode != "" {
+			return fmt.Errorf("template %s: partials cannot set a parse mode", name)
+		}
+		if _, err = t.partials.New(name).Parse(body); err != nil {
+			return err
+		}
+		t.escape(t.partials)
+		return nil
+	}
+
+	tmpl, err := t.partials.Clone()
+	if err != nil {
+		return err
+	}
+
+	tmpl = tmpl.Funcs(helperFuncs(parseMode)).Funcs(t.funcs)
+	if tmpl, err = tmpl.New(name).Parse(body); err != nil {
+		return err
+	}
+	t.escape(tmpl)
+
+	t.set[name] = &messageTemplate{tmpl: tmpl, parseMode: parseMode}
+	return nil
+}
+
+// Names returns the names of all templates that are not partials.
+func (t *Templates) Names() []string {
+	names := make([]string, 0, len(t.set))
+	for name := range t.set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks that every template only uses templates that are defined,
+// and that rendering it without data gives valid markup for its parse mode
+// (see format.Validate). Templates that cannot be rendered without data are
+// only checked for undefined templates.
+func (t *Templates) Validate() error {
+	var errs []error
+
+	for _, name := range t.Names() {
+		mt := t.set[name]
+		undefined := false
+		for _, used := range usedTemplates(mt.tmpl) {
+			if mt.tmpl.Lookup(used) == nil {
+				errs = append(errs, fmt.Errorf("template %s: uses undefined template %q", name, used))
+				undefined = true
+			}
+		}
+		if undefined {
+			continue
+		}
+
+		var sb strings.Builder
+		if err := mt.tmpl.Execute(&sb, nil); err != nil {
+			continue
+		}
+		if err := format.Validate(sb.String(), mt.parseMode); err != nil {
+			errs = append(errs, fmt.Errorf("template %s: %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Execute renders the template name with data.
+func (t *Templates) Execute(name string, data any) (format.Message, error) {
+	mt, ok := t.set[name]
+	if !ok {
+		return format.Message{}, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+
+	var sb strings.Builder
+	if err := mt.tmpl.Execute(&sb, data); err != nil {
+		return format.Message{}, err
+	}
+
+	return format.Message{Text: strings.TrimSpace(sb.String()), ParseMode: mt.parseMode}, nil
+}
+
+func isPartial(name string) bool {
+	return strings.HasPrefix(path.Base(name), "_")
+}
+
+// Split the metadata block off text.
+func parseTemplateMeta(text string) (parseMode, body string, err error) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	rest, ok := strings.CutPrefix(text, "---\n")
+	if !ok {
+		return "", text, nil
+	}
+
+	meta, body, ok := strings.Cut(rest, "\n---\n")
+	if !ok {
+		return "", "", errors.New("unterminated metadata block")
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(meta))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return "", "", fmt.Errorf("invalid metadata line %q", line)
+		}
+
+		switch key = strings.TrimSpace(key); key {
+		case "parse_mode":
+			parseMode = strings.TrimSpace(value)
+		default:
+			return "", "", fmt.Errorf("unknown metadata key %q", key)
+		}
+	}
+
+	switch parseMode {
+	case "", ParseModeHTML, ParseModeMarkdownV2:
+	default:
+		return "", "", fmt.Errorf("unsupported parse mode %q", parseMode)
+	}
+
+	return parseMode, body, nil
+}
+
+// Name of the function escaping the output of actions.
+const escapeFunc = "_escape"
+
+// Pipe the output of every action of the templates of tmpl through
+// escapeFunc, so that it is escaped for the parse mode of the template it
+// is executed in.
+func (t *Templates) escape(tmpl *template.Template) {
+	var walk func(tree *parse.Tree, n parse.Node)
+	walk = func(tree *parse.Tree, n parse.Node) {
+		switch n := n.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Nodes {
+				walk(tree, c)
+			}
+		case *parse.ActionNode:
+			// Actions declaring variables print nothing.
+			if len(n.Pipe.Decl) > 0 {
+				return
+			}
+			ident := parse.NewIdentifier(escapeFunc).SetTree(tree).SetPos(n.Pos)
+			n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+				NodeType: parse.NodeCommand,
+				Pos:      n.Pos,
+				Args:     []parse.Node{ident},
+			})
+		case *parse.IfNode:
+			walk(tree, n.List)
+			walk(tree, n.ElseList)
+		case *parse.RangeNode:
+			walk(tree, n.List)
+			walk(tree, n.ElseList)
+		case *parse.WithNode:
+			walk(tree, n.List)
+			walk(tree, n.ElseList)
+		}
+	}
+
+	for _, tt := range tmpl.Templates() {
+		if tt.Tree == nil || t.escaped[tt.Tree] {
+			continue
+		}
+		walk(tt.Tree, tt.Tree.Root)
+		t.escaped[tt.Tree] = true
+	}
+}
+
+// Names of the templates invoked by tmpl and the templates it defines.
+func usedTemplates(tmpl *template.Template) []string {
+	var names []string
+
+	var walk func(n parse.Node)
+	walk = func(n parse.Node) {
+		switch n := n.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Nodes {
+				walk(c)
+			}
+		case *parse.TemplateNode:
+			names = append(names, n.Name)
+		case *parse.IfNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.List)
+			walk(n.ElseList)
+		}
+	}
+
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			walk(t.Tree.Root)
+		}
+	}
+
+	return names
+}
+
+// Output of the template helpers. It is already escaped, so helpers do not
+// escape it again when they are nested.
+type templateText string
+
+// Template functions escaping and formatting for parseMode.
+func helperFuncs(parseMode string) template.FuncMap {
+	render := func(n format.Node) templateText {
+		switch parseMode {
+		case ParseModeHTML:
+			return templateText(format.HTML(n).Text)
+		case ParseModeMarkdownV2:
+			return templateText(format.MarkdownV2(n).Text)
+		}
+		return templateText(format.Entities(n).Text)
+	}
+
+	nodes := func(content []any) []any {
+		out := make([]any, len(content))
+		for i, c := range content {
+			if t, ok := c.(templateText); ok {
+				c = format.Raw(string(t))
+			}
+			out[i] = c
+		}
+		return out
+	}
+
+	wrap := func(f func(...any) format.Node) func(...any) templateText {
+		return func(content ...any) templateText { return render(f(nodes(content)...)) }
+	}
+
+	return template.FuncMap{
+		escapeFunc: func(v any) templateText {
+			switch v := v.(type) {
+			case nil:
+				return ""
+			case templateText:
+				return v
+			case format.Node:
+				return render(v)
+			}
+			return render(format.Plain(v))
+		},
+		"escape":        wrap(format.Plain),
+		"bold":          wrap(format.Bold),
+		"italic":        wrap(format.Italic),
+		"underline":     wrap(format.Underline),
+		"strikethrough": wrap(format.Strikethrough),
+		"spoiler":       wrap(format.Spoiler),
+		"code": func(v any) templateText {
+			return render(format.Code(fmt.Sprint(v)))
+		},
+		"pre": func(language string, v any) templateText {
+			return render(format.Pre(language, fmt.Sprint(v)))
+		},
+		"link": func(url string, content ...any) templateText {
+			return render(format.Link(url, nodes(content)...))
+		},
+		"mention": func(user *tgbotapi.User) templateText {
+			name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+			return render(format.Mention(user.ID, name))
+		},
+		"join": func(sep string, elems []string) string {
+			return strings.Join(elems, sep)
+		},
+		"date": func(layout string, t time.Time) string {
+			return t.Format(layout)
+		},
+	}
+}
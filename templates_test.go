@@ -0,0 +1,173 @@
+package tgbotapp_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"text/template"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	tgbotapp "github.com/nexoratech2025/go-telegram-bot-app"
+	"github.com/nexoratech2025/go-telegram-bot-app/format"
+)
+
+func TestTemplatesShouldRenderWithParseModeFromMetadata(t *testing.T) {
+	// Arrange
+	fsys := fstest.MapFS{
+		"welcome.tmpl":       {Data: []byte("---\nparse_mode: HTML\n---\nHello, {{bold .Name}} {{escape .Note}}!\n")},
+		"plain/bye.tmpl":     {Data: []byte("Bye {{.Name}}")},
+		"_footer.tmpl":       {Data: []byte(`{{define "footer"}}{{italic "bot"}}{{end}}`)},
+		"md.tmpl":            {Data: []byte("---\nparse_mode: MarkdownV2\n---\n{{bold (link .URL .Name)}} {{code .Note}}\n{{template \"footer\"}}")},
+		"ignored.txt":        {Data: []byte("{{")},
+		"plain/ignored.tmpl": {Data: []byte("{{")},
+	}
+
+	templates := tgbotapp.NewTemplates()
+
+	// Act
+	err := templates.ParseFS(fsys, "*.tmpl", "plain/bye.tmpl")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected templates to parse, found %v", err)
+	}
+	if err = templates.Validate(); err != nil {
+		t.Fatalf("Expected templates to be valid, found %v", err)
+	}
+
+	data := map[string]string{"Name": "a_b", "Note": "<x>", "URL": "https://x.y/(1)"}
+
+	msg, err := templates.Execute("welcome", data)
+	if err != nil || msg.Text != "Hello, <b>a_b</b> &lt;x&gt;!" || msg.ParseMode != tgbotapi.ModeHTML {
+		t.Errorf("Expected HTML message, found %+v, %v", msg, err)
+	}
+
+	msg, err = templates.Execute("md", data)
+	if err != nil || msg.Text != "*[a\\_b](https://x.y/(1\\))* `<x>`\n_bot_" || msg.ParseMode != tgbotapi.ModeMarkdownV2 {
+		t.Errorf("Expected MarkdownV2 message, found %q, %v", msg.Text, err)
+	}
+
+	msg, err = templates.Execute("plain/bye", data)
+	if err != nil || msg.Text != "Bye a_b" || msg.ParseMode != "" {
+		t.Errorf("Expected plain message, found %+v, %v", msg, err)
+	}
+}
+
+func TestTemplatesShouldNotExposePartials(t *testing.T) {
+	// Arrange
+	templates := tgbotapp.NewTemplates()
+	templates.Add("_part", `{{define "p"}}x{{end}}`)
+
+	// Act
+	_, err := templates.Execute("_part", nil)
+
+	// Assert
+	if !errors.Is(err, tgbotapp.ErrTemplateNotFound) {
+		t.Errorf("Expected ErrTemplateNotFound, found %v", err)
+	}
+}
+
+func TestTemplatesShouldRejectInvalidMetadata(t *testing.T) {
+	cases := map[string]string{
+		"unknown parse mode": "---\nparse_mode: BBCode\n---\nx",
+		"unknown key":        "---\ncolor: red\n---\nx",
+		"unterminated":       "---\nparse_mode: HTML\nx",
+		"syntax":             "{{if}}",
+	}
+
+	for name, text := range cases {
+		t.Run(name, func(t *testing.T) {
+			if err := tgbotapp.NewTemplates().Add("t", text); err == nil {
+				t.Errorf("Expected error for %q", text)
+			}
+		})
+	}
+}
+
+func TestTemplatesValidateShouldReportUndefinedTemplates(t *testing.T) {
+	// Arrange
+	templates := tgbotapp.NewTemplates()
+	templates.Add("t", `{{if .}}{{template "missing"}}{{end}}`)
+
+	// Act
+	err := templates.Validate()
+
+	// Assert
+	if err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("Expected undefined template to be reported, found %v", err)
+	}
+}
+
+func TestTemplatesShouldUseCustomFuncs(t *testing.T) {
+	// Arrange
+	templates := tgbotapp.NewTemplates().Funcs(template.FuncMap{"upper": strings.ToUpper})
+	templates.Add("t", `{{upper .}}`)
+
+	// Act
+	msg, err := templates.Execute("t", "hi")
+
+	// Assert
+	if err != nil || msg.Text != "HI" {
+		t.Errorf("Expected custom func to be used, found %q, %v", msg.Text, err)
+	}
+}
+
+func TestTemplatesShouldEscapeValuesForParseMode(t *testing.T) {
+	// Arrange
+	templates := tgbotapp.NewTemplates().Funcs(template.FuncMap{
+		"raw": func() format.Node { return format.Raw("<i>x</i>") },
+	})
+	templates.Add("_part", `{{define "name"}}{{.}}{{end}}`)
+	templates.Add("html", "---\nparse_mode: HTML\n---\n{{.}} {{template \"name\" .}} {{bold .}} {{raw}}")
+	templates.Add("md", "---\nparse_mode: MarkdownV2\n---\n{{range $i, $s := .}}{{$s}}{{end}}")
+
+	// Act
+	html, htmlErr := templates.Execute("html", "<a&b>")
+	md, mdErr := templates.Execute("md", []string{"a.b", "_c_"})
+
+	// Assert
+	expected := "&lt;a&amp;b&gt; &lt;a&amp;b&gt; <b>&lt;a&amp;b&gt;</b> <i>x</i>"
+	if htmlErr != nil || html.Text != expected {
+		t.Errorf("Expected %q, found %q, %v", expected, html.Text, htmlErr)
+	}
+	if mdErr != nil || md.Text != `a\.b\_c\_` {
+		t.Errorf("Expected MarkdownV2 values to be escaped, found %q, %v", md.Text, mdErr)
+	}
+}
+
+func TestTemplatesValidateShouldReportInvalidMarkup(t *testing.T) {
+	// Arrange
+	templates := tgbotapp.NewTemplates()
+	templates.Add("html", "---\nparse_mode: HTML\n---\n<b>unclosed {{.}}")
+	templates.Add("md", "---\nparse_mode: MarkdownV2\n---\nDone.")
+	templates.Add("ok", "---\nparse_mode: MarkdownV2\n---\n*{{.}}*\\.")
+
+	// Act
+	err := templates.Validate()
+
+	// Assert
+	if err == nil || !strings.Contains(err.Error(), "template html") || !strings.Contains(err.Error(), "template md") {
+		t.Fatalf("Expected invalid markup to be reported, found %v", err)
+	}
+	if strings.Contains(err.Error(), "template ok") {
+		t.Errorf("Expected valid template not to be reported, found %v", err)
+	}
+}
+
+func TestTemplatesShouldAcceptCRLFMetadata(t *testing.T) {
+	// Arrange
+	templates := tgbotapp.NewTemplates()
+
+	// Act
+	err := templates.Add("t", "---\r\nparse_mode: HTML\r\n---\r\nHi {{.}}\r\n")
+	msg, execErr := templates.Execute("t", "<x>")
+
+	// Assert
+	if err != nil || execErr != nil {
+		t.Fatalf("Expected CRLF template to parse, found %v, %v", err, execErr)
+	}
+	if msg.Text != "Hi &lt;x&gt;" || msg.ParseMode != tgbotapi.ModeHTML {
+		t.Errorf("Expected HTML message, found %+v", msg)
+	}
+}
@@ -0,0 +1,502 @@
+package testutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// Token accepted by FakeBotAPI.
+	FakeToken = "123456:fake-token"
+
+	// Longest time getUpdates waits for an update.
+	maxPollWait = 5 * time.Second
+)
+
+// Call is a request received by FakeBotAPI.
+type Call struct {
+	Method string
+	Params url.Values
+	// Content of the uploaded files by field name.
+	Files map[string][]byte
+	// Error code the call was answered with, 0 if it succeeded.
+	ErrorCode int
+}
+
+type fakeFile struct {
+	file    tgbotapi.File
+	content []byte
+}
+
+type fakeError struct {
+	code        int
+	description string
+	retryAfter  int
+}
+
+// FakeBotAPI is an in-process Bot API server for tests. It implements
+// getMe, getUpdates, getFile and file downloads, answers the other send and
+// edit methods with a message and all remaining methods with true, and
+// records every call. Point a bot at it with NewBotAPI, or with
+// tgbotapi.NewBotAPIWithAPIEndpoint(FakeToken, f.Endpoint()).
+type FakeBotAPI struct {
+	srv  *httptest.Server
+	done chan struct{}
+	once sync.Once
+
+	// User returned by getMe.
+	Bot tgbotapi.User
+
+	mu            sync.Mutex
+	calls         []Call
+	updates       []tgbotapi.Update
+	nextUpdateID  int
+	nextMessageID int
+	files         map[string]fakeFile
+	failures      map[string][]fakeError
+	chatFailures  map[int64]fakeError
+	changed       chan struct{}
+}
+
+// Start new server, closed when the test ends.
+func NewFakeBotAPI(t testing.TB) *FakeBotAPI {
+	f := &FakeBotAPI{
+		done:          make(chan struct{}),
+		Bot:           tgbotapi.User{ID: 123456, IsBot: true, FirstName: "Fake", UserName: "fake_bot"},
+		nextUpdateID:  1,
+		nextMessageID: 1,
+		files:         make(map[string]fakeFile),
+		failures:      make(map[string][]fakeError),
+		chatFailures:  make(map[int64]fakeError),
+		changed:       make(chan struct{}),
+	}
+
+	f.srv = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+// Endpoint for tgbotapi.NewBotAPIWithAPIEndpoint.
+func (f *FakeBotAPI) Endpoint() string {
+	return f.srv.URL + "/bot%s/%s"
+}
+
+// Endpoint of file downloads, such as for tgbotapp.WithFileEndpoint.
+func (f *FakeBotAPI) FileEndpoint() string {
+	return f.srv.URL + "/file/bot%s/%s"
+}
+
+// Return a bot using the server.
+func (f *FakeBotAPI) NewBotAPI(t testing.TB) *tgbotapi.BotAPI {
+	t.Helper()
+
+	bot, err := tgbotapi.NewBotAPIWithAPIEndpoint(FakeToken, f.Endpoint())
+	if err != nil {
+		t.Fatalf("Cannot create bot for fake Bot API: %v", err)
+	}
+	return bot
+}
+
+// Stop the server. Pending getUpdates calls return at once.
+func (f *FakeBotAPI) Close() {
+	f.once.Do(func() {
+		close(f.done)
+		f.srv.Close()
+	})
+}
+
+// Queue update for getUpdates. A zero UpdateID is replaced by the next id.
+func (f *FakeBotAPI) PushUpdate(update tgbotapi.Update) tgbotapi.Update {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if update.UpdateID == 0 {
+		update.UpdateID = f.nextUpdateID
+	}
+	f.nextUpdateID = max(f.nextUpdateID, update.UpdateID+1)
+	f.updates = append(f.updates, update)
+	f.notify()
+
+	return update
+}
+
+// Queue a text message sent by user userID in their private chat. Texts
+// starting with "/" are commands.
+func (f *FakeBotAPI) PushMessage(userID int64, text string) tgbotapi.Update {
+	msg := &tgbotapi.Message{
+		MessageID: f.messageID(),
+		From:      &tgbotapi.User{ID: userID, FirstName: "User"},
+		Chat:      &tgbotapi.Chat{ID: userID, Type: "private"},
+		Date:      int(time.Now().Unix()),
+		Text:      text,
+	}
+
+	if strings.HasPrefix(text, "/") {
+		command, _, _ := strings.Cut(text, " ")
+		msg.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(command)}}
+	}
+
+	return f.PushUpdate(tgbotapi.Update{Message: msg})
+}
+
+// Queue a press of an inline button with data by user userID.
+func (f *FakeBotAPI) PushCallback(userID int64, data string) tgbotapi.Update {
+	return f.PushUpdate(tgbotapi.Update{
+		CallbackQuery: &tgbotapi.CallbackQuery{
+			ID:   strconv.Itoa(f.messageID()),
+			From: &tgbotapi.User{ID: userID, FirstName: "User"},
+			Message: &tgbotapi.Message{
+				MessageID: f.messageID(),
+				From:      &f.Bot,
+				Chat:      &tgbotapi.Chat{ID: userID, Type: "private"},
+			},
+			Data: data,
+		},
+	})
+}
+
+// Make fileID available to getFile and downloads at path.
+func (f *FakeBotAPI) AddFile(fileID, path string, content []byte) {
+	f.AddFileWithSize(fileID, path, content, len(content))
+}
+
+// Same as AddFile, with getFile announcing size bytes instead of the length
+// of content. A size of 0 announces no size.
+func (f *FakeBotAPI) AddFileWithSize(fileID, path string, content []byte, size int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.files[fileID] = fakeFile{
+		file: tgbotapi.File{
+			FileID:       fileID,
+			FileUniqueID: "unique-" + fileID,
+			FileSize:     size,
+			FilePath:     path,
+		},
+		content: content,
+	}
+}
+
+// Answer the next call to method with an error.
+func (f *FakeBotAPI) FailNext(method string, code int, description string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[method] = append(f.failures[method], fakeError{code: code, description: description})
+}
+
+// Answer every call to chatID with an error, such as 403 for a user who
+// blocked the bot.
+func (f *FakeBotAPI) FailChat(chatID int64, code int, description string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.chatFailures[chatID] = fakeError{code: code, description: description}
+}
+
+// Answer the next call to method with a flood wait of retryAfter seconds.
+func (f *FakeBotAPI) FailNextWithRetryAfter(method string, retryAfter int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[method] = append(f.failures[method], fakeError{
+		code:        http.StatusTooManyRequests,
+		description: fmt.Sprintf("Too Many Requests: retry after %d", retryAfter),
+		retryAfter:  retryAfter,
+	})
+}
+
+// Return all calls except getUpdates.
+func (f *FakeBotAPI) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// Return the calls to method.
+func (f *FakeBotAPI) CallsTo(method string) []Call {
+	var calls []Call
+	for _, c := range f.Calls() {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// Wait until method was called n times in total and return the n-th call.
+func (f *FakeBotAPI) WaitForCall(t testing.TB, method string, n int, timeout time.Duration) Call {
+	t.Helper()
+
+	deadline := time.After(timeout)
+	for {
+		f.mu.Lock()
+		changed := f.changed
+		f.mu.Unlock()
+
+		if calls := f.CallsTo(method); len(calls) >= n {
+			return calls[n-1]
+		}
+
+		select {
+		case <-changed:
+		case <-deadline:
+			t.Fatalf("Expected %d calls to %s, found %d", n, method, len(f.CallsTo(method)))
+			return Call{}
+		}
+	}
+}
+
+// Wake up the goroutines waiting for a change. Must hold f.mu.
+func (f *FakeBotAPI) notify() {
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+func (f *FakeBotAPI) messageID() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := f.nextMessageID
+	f.nextMessageID++
+	return id
+}
+
+func (f *FakeBotAPI) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if path, ok := strings.CutPrefix(r.URL.Path, "/file/bot"+FakeToken+"/"); ok {
+		f.serveFile(w, path)
+		return
+	}
+
+	method, ok := strings.CutPrefix(r.URL.Path, "/bot"+FakeToken+"/")
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	call, err := parseCall(method, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Bad Request: "+err.Error())
+		return
+	}
+
+	if method == "getUpdates" {
+		f.getUpdates(w, r, call.Params)
+		return
+	}
+
+	f.mu.Lock()
+	var fail *fakeError
+	if failures := f.failures[method]; len(failures) > 0 {
+		fail = &failures[0]
+		f.failures[method] = failures[1:]
+	} else if chatID, err := strconv.ParseInt(call.Params.Get("chat_id"), 10, 64); err == nil {
+		if e, ok := f.chatFailures[chatID]; ok {
+			fail = &e
+		}
+	}
+	if fail != nil {
+		call.ErrorCode = fail.code
+	}
+	f.calls = append(f.calls, call)
+	f.notify()
+	f.mu.Unlock()
+
+	if fail != nil {
+		writeFailure(w, *fail)
+		return
+	}
+
+	switch method {
+	case "getMe":
+		writeResult(w, f.Bot)
+	case "sendMessage":
+		msg := f.newMessage(call.Params)
+		msg.Text = call.Params.Get("text")
+		writeResult(w, msg)
+	case "editMessageText":
+		msg := f.newMessage(call.Params)
+		msg.MessageID, _ = strconv.Atoi(call.Params.Get("message_id"))
+		msg.Text = call.Params.Get("text")
+		writeResult(w, msg)
+	case "sendDocument":
+		writeResult(w, f.sendDocument(call))
+	case "sendMediaGroup":
+		writeResult(w, f.sendMediaGroup(call))
+	case "getFile":
+		f.getFile(w, call.Params.Get("file_id"))
+	default:
+		switch {
+		case strings.HasPrefix(method, "send") && method != "sendChatAction":
+			msg := f.newMessage(call.Params)
+			msg.Caption = call.Params.Get("caption")
+			writeResult(w, msg)
+		case strings.HasPrefix(method, "edit"):
+			msg := f.newMessage(call.Params)
+			msg.MessageID, _ = strconv.Atoi(call.Params.Get("message_id"))
+			writeResult(w, msg)
+		default:
+			writeResult(w, true)
+		}
+	}
+}
+
+func parseCall(method string, r *http.Request) (Call, error) {
+	call := Call{Method: method}
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			return call, err
+		}
+		call.Files = make(map[string][]byte)
+		for field, headers := range r.MultipartForm.File {
+			file, err := headers[0].Open()
+			if err != nil {
+				return call, err
+			}
+			b, err := io.ReadAll(file)
+			file.Close()
+			if err != nil {
+				return call, err
+			}
+			call.Files[field] = b
+		}
+	} else if err := r.ParseForm(); err != nil {
+		return call, err
+	}
+
+	call.Params = r.Form
+	return call, nil
+}
+
+func (f *FakeBotAPI) getUpdates(w http.ResponseWriter, r *http.Request, params url.Values) {
+	offset, _ := strconv.Atoi(params.Get("offset"))
+	timeout, _ := strconv.Atoi(params.Get("timeout"))
+	deadline := time.After(min(time.Duration(timeout)*time.Second, maxPollWait))
+
+	for {
+		f.mu.Lock()
+		// Updates before offset are confirmed.
+		pending := f.updates[:0]
+		for _, u := range f.updates {
+			if u.UpdateID >= offset {
+				pending = append(pending, u)
+			}
+		}
+		f.updates = pending
+		updates := append([]tgbotapi.Update{}, pending...)
+		changed := f.changed
+		f.mu.Unlock()
+
+		if len(updates) > 0 || timeout == 0 {
+			writeResult(w, updates)
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-deadline:
+			writeResult(w, updates)
+			return
+		case <-r.Context().Done():
+			return
+		case <-f.done:
+			writeResult(w, updates)
+			return
+		}
+	}
+}
+
+func (f *FakeBotAPI) newMessage(params url.Values) tgbotapi.Message {
+	chatID, _ := strconv.ParseInt(params.Get("chat_id"), 10, 64)
+	return tgbotapi.Message{
+		MessageID: f.messageID(),
+		From:      &f.Bot,
+		Chat:      &tgbotapi.Chat{ID: chatID},
+		Date:      int(time.Now().Unix()),
+	}
+}
+
+func (f *FakeBotAPI) sendDocument(call Call) tgbotapi.Message {
+	msg := f.newMessage(call.Params)
+	msg.Caption = call.Params.Get("caption")
+
+	fileID := call.Params.Get("document")
+	if content, ok := call.Files["document"]; ok {
+		fileID = fmt.Sprintf("document-%d", msg.MessageID)
+		f.AddFile(fileID, "documents/"+fileID, content)
+	}
+
+	msg.Document = &tgbotapi.Document{FileID: fileID, FileUniqueID: "unique-" + fileID}
+	return msg
+}
+
+func (f *FakeBotAPI) sendMediaGroup(call Call) []tgbotapi.Message {
+	var media []json.RawMessage
+	json.Unmarshal([]byte(call.Params.Get("media")), &media)
+
+	messages := make([]tgbotapi.Message, len(media))
+	for i := range messages {
+		messages[i] = f.newMessage(call.Params)
+		messages[i].MediaGroupID = "album"
+	}
+	return messages
+}
+
+func (f *FakeBotAPI) getFile(w http.ResponseWriter, fileID string) {
+	f.mu.Lock()
+	file, ok := f.files[fileID]
+	f.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusBadRequest, "Bad Request: invalid file_id")
+		return
+	}
+	writeResult(w, file.file)
+}
+
+func (f *FakeBotAPI) serveFile(w http.ResponseWriter, path string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, file := range f.files {
+		if file.file.FilePath == path {
+			w.Write(file.content)
+			return
+		}
+	}
+	http.NotFound(w, nil)
+}
+
+func writeResult(w http.ResponseWriter, result any) {
+	b, err := json.Marshal(result)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tgbotapi.APIResponse{Ok: true, Result: b})
+}
+
+func writeError(w http.ResponseWriter, code int, description string) {
+	writeFailure(w, fakeError{code: code, description: description})
+}
+
+func writeFailure(w http.ResponseWriter, e fakeError) {
+	res := tgbotapi.APIResponse{Ok: false, ErrorCode: e.code, Description: e.description}
+	if e.retryAfter > 0 {
+		res.Parameters = &tgbotapi.ResponseParameters{RetryAfter: e.retryAfter}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.code)
+	json.NewEncoder(w).Encode(res)
+}
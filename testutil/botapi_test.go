@@ -0,0 +1,207 @@
+package testutil_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nexoratech2025/go-telegram-bot-app/testutil"
+)
+
+func TestFakeBotAPIShouldAnswerAndRecordCalls(t *testing.T) {
+	// Arrange
+	f := testutil.NewFakeBotAPI(t)
+	bot := f.NewBotAPI(t)
+
+	// Act
+	msg, err := bot.Send(tgbotapi.NewMessage(42, "hello"))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected message to be sent, found %v", err)
+	}
+	if msg.Chat.ID != 42 || msg.Text != "hello" || msg.MessageID == 0 {
+		t.Errorf("Expected sent message, found %+v", msg)
+	}
+	if bot.Self.UserName != f.Bot.UserName {
+		t.Errorf("Expected bot %s, found %s", f.Bot.UserName, bot.Self.UserName)
+	}
+
+	calls := f.CallsTo("sendMessage")
+	if len(calls) != 1 || calls[0].Params.Get("text") != "hello" {
+		t.Errorf("Expected recorded sendMessage call, found %+v", calls)
+	}
+}
+
+func TestFakeBotAPIShouldAnswerOtherMethods(t *testing.T) {
+	// Arrange
+	f := testutil.NewFakeBotAPI(t)
+	bot := f.NewBotAPI(t)
+
+	// Act
+	photo, photoErr := bot.Send(tgbotapi.NewPhoto(42, tgbotapi.FileID("photo")))
+	album, albumErr := bot.SendMediaGroup(tgbotapi.NewMediaGroup(42, []interface{}{
+		tgbotapi.NewInputMediaPhoto(tgbotapi.FileID("a")),
+		tgbotapi.NewInputMediaPhoto(tgbotapi.FileID("b")),
+	}))
+	_, actionErr := bot.Request(tgbotapi.NewChatAction(42, tgbotapi.ChatTyping))
+
+	// Assert
+	if photoErr != nil || photo.Chat.ID != 42 || photo.MessageID == 0 {
+		t.Errorf("Expected sent photo, found %+v, %v", photo, photoErr)
+	}
+	if albumErr != nil || len(album) != 2 {
+		t.Errorf("Expected %d sent album items, found %d, %v", 2, len(album), albumErr)
+	}
+	if actionErr != nil || len(f.CallsTo("sendChatAction")) != 1 {
+		t.Errorf("Expected recorded chat action, found %v", actionErr)
+	}
+}
+
+func TestFakeBotAPIShouldServeQueuedUpdates(t *testing.T) {
+	// Arrange
+	f := testutil.NewFakeBotAPI(t)
+	bot := f.NewBotAPI(t)
+	f.PushMessage(7, "/start now")
+	f.PushCallback(7, "yes")
+
+	// Act
+	updates, err := bot.GetUpdates(tgbotapi.UpdateConfig{Offset: 0, Timeout: 1})
+	confirmed, _ := bot.GetUpdates(tgbotapi.UpdateConfig{Offset: updates[len(updates)-1].UpdateID + 1})
+
+	// Assert
+	if err != nil || len(updates) != 2 {
+		t.Fatalf("Expected 2 updates, found %d, %v", len(updates), err)
+	}
+	if !updates[0].Message.IsCommand() || updates[0].Message.Command() != "start" {
+		t.Errorf("Expected start command, found %+v", updates[0].Message)
+	}
+	if updates[1].CallbackQuery.Data != "yes" {
+		t.Errorf("Expected callback data yes, found %+v", updates[1].CallbackQuery)
+	}
+	if len(confirmed) != 0 {
+		t.Errorf("Expected confirmed updates to be dropped, found %d", len(confirmed))
+	}
+}
+
+func TestFakeBotAPIShouldStoreUploadedDocuments(t *testing.T) {
+	// Arrange
+	f := testutil.NewFakeBotAPI(t)
+	bot := f.NewBotAPI(t)
+
+	// Act
+	msg, err := bot.Send(tgbotapi.NewDocument(1, tgbotapi.FileBytes{Name: "a.txt", Bytes: []byte("content")}))
+	if err != nil {
+		t.Fatalf("Expected document to be sent, found %v", err)
+	}
+	file, err := bot.GetFile(tgbotapi.FileConfig{FileID: msg.Document.FileID})
+	if err != nil {
+		t.Fatalf("Expected file to be found, found %v", err)
+	}
+	resp, err := http.Get(strings.Replace(f.FileEndpoint(), "%s/%s", testutil.FakeToken+"/"+file.FilePath, 1))
+	if err != nil {
+		t.Fatalf("Expected file to be downloaded, found %v", err)
+	}
+	defer resp.Body.Close()
+	content, _ := io.ReadAll(resp.Body)
+
+	// Assert
+	if string(content) != "content" {
+		t.Errorf("Expected uploaded content, found %q", content)
+	}
+	if calls := f.CallsTo("sendDocument"); len(calls) != 1 || string(calls[0].Files["document"]) != "content" {
+		t.Errorf("Expected recorded upload, found %+v", calls)
+	}
+}
+
+func TestFakeBotAPIShouldFailNextCall(t *testing.T) {
+	// Arrange
+	f := testutil.NewFakeBotAPI(t)
+	bot := f.NewBotAPI(t)
+	f.FailNext("sendMessage", 403, "Forbidden: bot was blocked by the user")
+
+	// Act
+	_, err := bot.Send(tgbotapi.NewMessage(1, "first"))
+	_, retryErr := bot.Send(tgbotapi.NewMessage(1, "second"))
+
+	// Assert
+	apiErr, ok := err.(*tgbotapi.Error)
+	if !ok || apiErr.Code != 403 {
+		t.Errorf("Expected 403 error, found %v", err)
+	}
+	if retryErr != nil {
+		t.Errorf("Expected second call to succeed, found %v", retryErr)
+	}
+}
+
+func TestFakeBotAPIShouldFailEveryCallToChat(t *testing.T) {
+	// Arrange
+	f := testutil.NewFakeBotAPI(t)
+	bot := f.NewBotAPI(t)
+	f.FailChat(2, 403, "Forbidden: bot was blocked by the user")
+
+	// Act
+	_, okErr := bot.Send(tgbotapi.NewMessage(1, "hello"))
+	_, firstErr := bot.Send(tgbotapi.NewMessage(2, "hello"))
+	_, secondErr := bot.Send(tgbotapi.NewMessage(2, "hello"))
+
+	// Assert
+	if okErr != nil {
+		t.Errorf("Expected message to chat 1 to be sent, found %v", okErr)
+	}
+	if firstErr == nil || secondErr == nil {
+		t.Errorf("Expected messages to chat 2 to fail, found %v, %v", firstErr, secondErr)
+	}
+	calls := f.CallsTo("sendMessage")
+	if len(calls) != 3 || calls[0].ErrorCode != 0 || calls[1].ErrorCode != 403 {
+		t.Errorf("Expected recorded error codes, found %+v", calls)
+	}
+}
+
+func TestFakeBotAPIShouldFailWithRetryAfter(t *testing.T) {
+	// Arrange
+	f := testutil.NewFakeBotAPI(t)
+	f.FailNextWithRetryAfter("sendMessage", 7)
+
+	// Act
+	resp, err := http.PostForm(fmt.Sprintf(f.Endpoint(), testutil.FakeToken, "sendMessage"), url.Values{"chat_id": {"1"}, "text": {"hi"}})
+	if err != nil {
+		t.Fatalf("Expected request to be answered, found %v", err)
+	}
+	defer resp.Body.Close()
+
+	var res tgbotapi.APIResponse
+	json.NewDecoder(resp.Body).Decode(&res)
+
+	// Assert
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429, found %d", resp.StatusCode)
+	}
+	if res.ErrorCode != http.StatusTooManyRequests || res.Parameters == nil || res.Parameters.RetryAfter != 7 {
+		t.Errorf("Expected flood wait of 7 seconds, found %+v", res)
+	}
+}
+
+func TestFakeBotAPIWaitForCallShouldWaitForAsyncCalls(t *testing.T) {
+	// Arrange
+	f := testutil.NewFakeBotAPI(t)
+	bot := f.NewBotAPI(t)
+
+	// Act
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		bot.Request(tgbotapi.NewCallback("1", "done"))
+	}()
+	call := f.WaitForCall(t, "answerCallbackQuery", 1, time.Second)
+
+	// Assert
+	if call.Params.Get("text") != "done" {
+		t.Errorf("Expected callback answer, found %+v", call)
+	}
+}
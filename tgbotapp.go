@@ -4,12 +4,13 @@ import (
 	"errors"
 	"log/slog"
 	"sync"
+	"time"
 
 	"context"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nexoratech2025/go-telegram-bot-app/i18n"
 	"github.com/nexoratech2025/go-telegram-bot-app/session"
-	"github.com/nexoratech2025/go-telegram-bot-app/util"
 )
 
 var (
@@ -20,14 +21,38 @@ var (
 type OptionFunc func(*Application)
 
 type Application struct {
-	middlewares *MiddlewareChain
-	handler     HandlerFunc
-	wg          sync.WaitGroup
+	middlewares       *MiddlewareChain
+	wg                sync.WaitGroup
+	senderMiddlewares []SenderMiddleware
+	rateLimits        *RateLimits
+	// Session middleware added by UseSession, also used by timeout handlers.
+	session Middleware
 
 	SessionManager session.SessionManager[int64]
 	Logger         *slog.Logger
 	Router         Router
 	BotAPI         *tgbotapi.BotAPI
+	// Optional sender of outbound requests, see WithSender. Defaults to a
+	// BotSender of BotAPI.
+	Sender Sender
+	// Optional scheduler all outbound requests of handlers go through.
+	Outbound *OutboundScheduler
+	// Retry policy for outbound requests. The zero value does not retry.
+	RetryPolicy RetryPolicy
+	// Message texts longer than SplitLimit UTF-16 code units of visible text
+	// are sent as several messages. Zero disables splitting.
+	SplitLimit int
+	// Message templates used by HandlerContext.SendTemplate.
+	Templates *Templates
+	// Message catalogs used by HandlerContext.T and for command descriptions.
+	I18n *i18n.Bundle
+	// Optional aggregator handling albums as single updates.
+	MediaGroups *MediaGroupAggregator
+	// Format of the file download URLs, see WithFileEndpoint.
+	FileEndpoint string
+	// Optional scheduler of time-driven jobs, run while the application is
+	// started.
+	Jobs *JobScheduler
 }
 
 // Return completely new application with no configuration.
@@ -51,6 +76,83 @@ func defaultOptions(a *Application) {
 	a.Logger = slog.Default()
 	a.Router = NewRouteTable()
 	a.SessionManager = NewDefaultInMemoryManager()
+	a.RetryPolicy = DefaultRetryPolicy()
+}
+
+// Send outbound requests through a scheduler enforcing limits. The scheduler
+// is started by Start and closed once the application stopped.
+func WithRateLimits(limits RateLimits) OptionFunc {
+	return func(a *Application) {
+		a.rateLimits = &limits
+	}
+}
+
+// Retry failed outbound requests with p. See also WithCallRetry.
+func WithRetryPolicy(p RetryPolicy) OptionFunc {
+	return func(a *Application) {
+		a.RetryPolicy = p
+	}
+}
+
+// Split message texts sent by handlers that are longer than limit UTF-16
+// code units of visible text into several messages. A limit of 0 uses MaxMessageLength.
+func WithMessageSplitting(limit int) OptionFunc {
+	return func(a *Application) {
+		if limit <= 0 || limit > MaxMessageLength {
+			limit = MaxMessageLength
+		}
+		a.SplitLimit = limit
+	}
+}
+
+// Use t for HandlerContext.SendTemplate. The templates are validated when
+// the application starts.
+func WithTemplates(t *Templates) OptionFunc {
+	return func(a *Application) {
+		a.Templates = t
+	}
+}
+
+// Handle the items of an album as one update, once no new item arrived for
+// window. Only applies to applications created with Default; otherwise use
+// the aggregator's middleware before all others.
+func WithMediaGroups(window time.Duration) OptionFunc {
+	return func(a *Application) {
+		a.MediaGroups = NewMediaGroupAggregator(window)
+	}
+}
+
+// Download files from endpoint instead of tgbotapi.FileEndpoint, for a local
+// Bot API server. endpoint is formatted with the token and the file path,
+// such as "http://localhost:8081/file/bot%s/%s".
+func WithFileEndpoint(endpoint string) OptionFunc {
+	return func(a *Application) {
+		a.FileEndpoint = endpoint
+	}
+}
+
+// Run jobs with a scheduler created with opts. Applications created with
+// Default also cancel the timeout of a chat when it sends an update;
+// otherwise use the scheduler's TimeoutMiddleware.
+func WithJobs(opts ...JobOption) OptionFunc {
+	return func(a *Application) {
+		a.Jobs = NewJobScheduler(a, opts...)
+	}
+}
+
+// Set lifecycle hooks on the application's session manager. Must be applied
+// after the session manager is set.
+func WithSessionHooks(hooks session.Hooks[int64]) OptionFunc {
+	return func(a *Application) {
+		h, ok := a.SessionManager.(session.Hookable[int64])
+		if !ok {
+			if a.Logger != nil {
+				a.Logger.Warn("Session manager does not support hooks.")
+			}
+			return
+		}
+		h.SetHooks(hooks)
+	}
 }
 
 // Return new application with default configured Middlewares (Session and Router)
@@ -61,11 +163,20 @@ func Default(botAPI *tgbotapi.BotAPI, opts ...OptionFunc) *Application {
 	options = append(options, opts...)
 
 	app := New(botAPI, options...)
+	if app.MediaGroups != nil {
+		app.Use(app.MediaGroups.Middleware())
+	}
+	if app.Jobs != nil {
+		app.Use(app.Jobs.TimeoutMiddleware())
+	}
 	app.UseSession()
 	app.UseRouting()
 	return app
 }
 
+// Register the handler of command name. If the application has message
+// catalogs, the description is translated with the key "commands.<name>" for
+// the command lists of each language.
 func (a *Application) RegisterCommand(name string, description string, handler HandlerFunc) error {
 
 	botCommands = append(botCommands, tgbotapi.BotCommand{
@@ -92,6 +203,33 @@ func (a *Application) RegisterDocumentByType(docType string, handler HandlerFunc
 	return a.Router.AddHandler(docType, DocumentHandler, handler)
 }
 
+// Register the handler of whole albums. Requires WithMediaGroups; albums
+// without this handler are routed like their first item.
+func (a *Application) RegisterMediaGroup(handler HandlerFunc) error {
+	return a.Router.AddHandler(MediaGroupHandlerName, DocumentHandler, handler)
+}
+
+// Register the handler run by HandlerContext.ScheduleTimeout when a chat did
+// not answer in time. Requires WithJobs.
+func (a *Application) RegisterTimeout(name string, handler HandlerFunc) error {
+	return a.Router.AddHandler(name, TimeoutHandler, handler)
+}
+
+// Register fn as the function of the jobs named name. Requires WithJobs.
+func (a *Application) RegisterJob(name string, fn JobFunc) error {
+	if a.Jobs == nil {
+		return ErrNoJobScheduler
+	}
+	return a.Jobs.Register(name, fn)
+}
+
+// Register the handler that asks the user for input of state. It is run by
+// BackHandler and CancelHandler when the state becomes current again. Use
+// RootPrompt for the empty state.
+func (a *Application) RegisterPrompt(state string, handler HandlerFunc) error {
+	return a.Router.AddHandler(state, PromptHandler, handler)
+}
+
 func (a *Application) Use(middlewares ...Middleware) {
 	a.middlewares.Append(middlewares...)
 }
@@ -102,9 +240,12 @@ func (a *Application) UseRouting() {
 
 }
 
-func (a *Application) UseSession() {
+// Add the session middleware, see SessionMiddleware. Timeout handlers load
+// their session with the same options.
+func (a *Application) UseSession(opts ...SessionOption) {
 
-	a.middlewares.Append(SessionMiddleware(a.SessionManager))
+	a.session = SessionMiddleware(a.SessionManager, opts...)
+	a.middlewares.Append(a.session)
 
 }
 
@@ -114,7 +255,18 @@ func (a *Application) Start(ctx context.Context) error {
 
 	a.Logger.InfoContext(ctx, "Starting application...")
 
-	err := a.initBotCommands()
+	if a.Templates != nil {
+		if err := a.Templates.Validate(); err != nil {
+			a.Logger.ErrorContext(ctx, "Invalid message templates.", "error_detail", err)
+			return err
+		}
+	}
+
+	if a.rateLimits != nil {
+		a.Outbound = NewOutboundScheduler(*a.rateLimits)
+	}
+
+	err := a.initBotCommands(ctx)
 	if err != nil {
 		a.Logger.ErrorContext(ctx, "Cannot set commands list.", "error_detail", err)
 	} else {
@@ -124,8 +276,19 @@ func (a *Application) Start(ctx context.Context) error {
 	updateCfg := tgbotapi.NewUpdate(0)
 	updateCfg.Timeout = 60
 
+	// Long polling bypasses the sender, see Sender.
 	updates := a.BotAPI.GetUpdatesChan(updateCfg)
 
+	if a.Jobs != nil {
+		a.wg.Add(1)
+		go func() {
+			defer a.wg.Done()
+			if err := a.Jobs.Run(ctx); err != nil {
+				a.Logger.ErrorContext(ctx, "Cannot run jobs.", "error_detail", err)
+			}
+		}()
+	}
+
 	// Poll loop
 	a.wg.Add(1)
 	go func() {
@@ -146,6 +309,11 @@ func (a *Application) Start(ctx context.Context) error {
 	<-ctx.Done()
 
 	a.wg.Wait()
+
+	// Jobs may send until they returned.
+	if a.Outbound != nil {
+		a.Outbound.Close()
+	}
 	return nil
 
 }
@@ -153,6 +321,9 @@ func (a *Application) Start(ctx context.Context) error {
 func (a *Application) shutdown() {
 	a.Logger.Info("Shutting Down the application...")
 	a.BotAPI.StopReceivingUpdates()
+	if a.MediaGroups != nil {
+		a.MediaGroups.Flush()
+	}
 	a.Logger.Info("Application stopped successfully.")
 
 }
@@ -164,8 +335,8 @@ func (a *Application) handleUpdate(ctx context.Context, update *tgbotapi.Update)
 	f := a.middlewares.Wrap(func(ctx *BotContext) {
 		a.Logger.InfoContext(ctx.Ctx, "Processing update.", "from", ctx.Update.SentFrom().ID)
 
-		if a.handler != nil {
-			a.handler(ctx)
+		if ctx.handler != nil {
+			ctx.handler(ctx)
 		} else {
 			a.Logger.ErrorContext(ctx.Ctx, "Error: Default handler should be set in routing middleware.")
 		}
@@ -175,26 +346,40 @@ func (a *Application) handleUpdate(ctx context.Context, update *tgbotapi.Update)
 
 }
 
-func (a *Application) initBotCommands() error {
+func (a *Application) initBotCommands(ctx context.Context) error {
 
 	if len(botCommands) < 1 {
 		a.Logger.Warn("No bot commands found.")
 		return nil
 	}
 
-	cmds := tgbotapi.NewSetMyCommands(botCommands...)
+	if err := a.setMyCommands(ctx, tgbotapi.NewSetMyCommands(a.localizedCommands("")...)); err != nil {
+		return err
+	}
+
+	for _, locale := range a.commandLocales() {
+		cmds := tgbotapi.NewSetMyCommands(a.localizedCommands(locale)...)
+		cmds.LanguageCode = i18n.Base(locale)
+		if err := a.setMyCommands(ctx, cmds); err != nil {
+			return err
+		}
+	}
+
+	return nil
+
+}
+
+func (a *Application) setMyCommands(ctx context.Context, cmds tgbotapi.SetMyCommandsConfig) error {
 
-	// tgbotapi Send method handles message response only.
-	// setMyCommands method return boolean.
-	// Thus custom setMyCommand function is used here.
+	// setMyCommands method return boolean, not a message.
 
-	ok, err := util.SendSetMyCommands(*a.BotAPI, cmds)
+	res, err := a.newSender(nil).Request(ctx, 0, cmds)
 
 	if err != nil {
 		return err
 	}
 
-	if !ok {
+	if !res.Ok {
 		return errors.New("Cannot set command.")
 	}
 	return nil
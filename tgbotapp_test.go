@@ -1,69 +1,89 @@
 package tgbotapp_test
 
 import (
-	"os"
+	"context"
+	"strings"
 	"testing"
+	"time"
 
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	_ "github.com/joho/godotenv/autoload"
 	tgbotapp "github.com/nexoratech2025/go-telegram-bot-app"
+	"github.com/nexoratech2025/go-telegram-bot-app/testutil"
 )
 
 const (
-	botTokenEnvKey = "BOT_TOKEN"
-
 	expectsNoError   = "Should not return error. Got error: %#v"
 	expectsError     = "Should return error. got no error"
 	expectsErrorType = "Should return error type %#v. Got error type %#v"
 	expectsNotNil    = "Expects %s to not nil."
 )
 
+// Start app in the background and return a function stopping it and
+// returning the error of Start.
+func startApp(t *testing.T, app *tgbotapp.Application) (stop func() error) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+	go func() {
+		done <- app.Start(ctx)
+	}()
+
+	return func() error {
+		cancel()
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected application to stop")
+			return nil
+		}
+	}
+}
+
 func TestApplicationShouldStartCorrectly(t *testing.T) {
 	// Arrange
+	f := testutil.NewFakeBotAPI(t)
+	app := tgbotapp.Default(f.NewBotAPI(t))
 
-	token := os.Getenv(botTokenEnvKey)
-	botAPI, err := tgbotapi.NewBotAPI(token)
-
-	if err != nil {
-		t.Errorf("Expected botAPI to initialised. Received Error: %#v", err)
-	}
-
-	app := tgbotapp.Default(botAPI)
+	handled := make(chan struct{}, 1)
+	app.Use(func(ctx *tgbotapp.BotContext, next tgbotapp.HandlerFunc) {
+		handled <- struct{}{}
+	})
 
 	// Act
+	stop := startApp(t, app)
+	f.PushMessage(7, "hello")
 
-	err = app.Start(t.Context())
-
-	//Assert
+	select {
+	case <-handled:
+	case <-time.After(5 * time.Second):
+		t.Error("Expected update to be handled")
+	}
+	err := stop()
 
+	// Assert
 	if err != nil {
 		t.Errorf("Expected app to start smoothly. found error: %#v", err)
 	}
-
 }
 
 func TestApplicationShouldRegisterCommandCorrectly(t *testing.T) {
 	// Arrange
-
-	token := os.Getenv(botTokenEnvKey)
-	botAPI, err := tgbotapi.NewBotAPI(token)
-
-	if err != nil {
-		t.Errorf("Expected botAPI to initialised. Received Error: %#v", err)
-	}
-
-	app := tgbotapp.Default(botAPI)
+	f := testutil.NewFakeBotAPI(t)
+	app := tgbotapp.Default(f.NewBotAPI(t))
 
 	app.RegisterCommand("ping", "pong", func(bc *tgbotapp.BotContext) {})
 
 	// Act
+	stop := startApp(t, app)
+	call := f.WaitForCall(t, "setMyCommands", 1, 5*time.Second)
+	err := stop()
 
-	err = app.Start(t.Context())
-
-	//Assert
-
+	// Assert
 	if err != nil {
 		t.Errorf("Expected app to start smoothly. found error: %#v", err)
 	}
-
+	if !strings.Contains(call.Params.Get("commands"), `"command":"ping"`) {
+		t.Errorf("Expected ping command to be registered, found %v", call.Params)
+	}
 }
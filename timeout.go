@@ -0,0 +1,106 @@
+package tgbotapp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Name of the job running timeout handlers.
+const timeoutJobName = "tgbotapp.timeout"
+
+type timeoutPayload struct {
+	Handler string `json:"handler"`
+	State   string `json:"state"`
+}
+
+func timeoutJobID(chatID int64) string {
+	return "timeout:" + strconv.FormatInt(chatID, 10)
+}
+
+// Run the timeout handler name, registered with RegisterTimeout, if the
+// current chat sends no update within d. The handler runs with the session
+// of the chat, unless its state changed meanwhile. A chat has at most one
+// timeout; scheduling another one replaces it.
+func (h *HandlerContext) ScheduleTimeout(d time.Duration, name string) error {
+	if h.app == nil || h.app.Jobs == nil {
+		return ErrNoJobScheduler
+	}
+
+	var state string
+	if h.Session != nil {
+		state = h.Session.CurrentState()
+	}
+
+	payload, err := json.Marshal(timeoutPayload{Handler: name, State: state})
+	if err != nil {
+		return err
+	}
+
+	chatID := h.GetChatID()
+	_, err = h.app.Jobs.After(h.Ctx, d, Job{
+		ID:      timeoutJobID(chatID),
+		Name:    timeoutJobName,
+		ChatID:  chatID,
+		Payload: string(payload),
+	})
+	return err
+}
+
+// Cancel the timeout of the current chat, if any.
+func (h *HandlerContext) CancelTimeout() error {
+	if h.app == nil || h.app.Jobs == nil {
+		return ErrNoJobScheduler
+	}
+
+	err := h.app.Jobs.Cancel(h.Ctx, timeoutJobID(h.GetChatID()))
+	if errors.Is(err, ErrJobNotFound) {
+		return nil
+	}
+	return err
+}
+
+// Cancel the timeout of the chat of each update before it is handled.
+func (s *JobScheduler) TimeoutMiddleware() Middleware {
+	return func(ctx *BotContext, next HandlerFunc) {
+		if chat := ctx.Update.FromChat(); chat != nil {
+			err := s.Cancel(ctx.Ctx, timeoutJobID(chat.ID))
+			if err != nil && !errors.Is(err, ErrJobNotFound) {
+				ctx.Logger().ErrorContext(ctx.Ctx, "Cannot cancel timeout.", "chat_id", chat.ID, "error_detail", err)
+			}
+		}
+		next(ctx)
+	}
+}
+
+func (s *JobScheduler) runTimeout(jc *JobContext) error {
+	var payload timeoutPayload
+	if err := json.Unmarshal([]byte(jc.Job.Payload), &payload); err != nil {
+		return err
+	}
+
+	info, ok := s.app.Router.GetHandler(payload.Handler, TimeoutHandler)
+	if !ok {
+		return fmt.Errorf("No timeout handler found for name %s.", payload.Handler)
+	}
+
+	ctx := NewBotContext(WithPriority(jc.Ctx, PriorityNormal), s.app, chatUpdate(jc.Job.ChatID))
+
+	// Timeouts load the session like updates do.
+	sessions := s.app.session
+	if sessions == nil {
+		sessions = SessionMiddleware(s.app.SessionManager)
+	}
+
+	sessions(ctx, func(ctx *BotContext) {
+		if ctx.Session != nil && ctx.Session.CurrentState() != payload.State {
+			jc.Logger.InfoContext(ctx.Ctx, "Conversation moved on, timeout skipped.", "chat_id", jc.Job.ChatID)
+			return
+		}
+		info.Func(ctx)
+	})
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package tgbotapp_test
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	tgbotapp "github.com/nexoratech2025/go-telegram-bot-app"
+	"github.com/nexoratech2025/go-telegram-bot-app/testutil"
+)
+
+// Return an application with a running job scheduler using a fake clock and
+// a handler context of chat 1 in state.
+func newTimeoutHandlerContext(t *testing.T, state string) (*tgbotapp.HandlerContext, *tgbotapp.Application, *testutil.FakeClock) {
+	t.Helper()
+
+	f := testutil.NewFakeBotAPI(t)
+	clock := testutil.NewFakeClock(jobsEpoch)
+	app := tgbotapp.Default(f.NewBotAPI(t), tgbotapp.WithJobs(tgbotapp.WithClock(clock)))
+	runJobs(t, app.Jobs)
+
+	sess, _ := app.SessionManager.GetOrCreate(1)
+	sess.SetState(state)
+	app.SessionManager.Set(1, sess)
+
+	ctx := tgbotapp.NewBotContext(t.Context(), app, newChatUpdate(1, ""))
+	ctx.Session = sess
+	return tgbotapp.NewHandlerContext(ctx, "test"), app, clock
+}
+
+func recordTimeout(t *testing.T, app *tgbotapp.Application) <-chan string {
+	t.Helper()
+
+	states := make(chan string, 1)
+	err := app.RegisterTimeout("nudge", func(ctx *tgbotapp.BotContext) {
+		states <- ctx.Session.CurrentState()
+	})
+	if err != nil {
+		t.Fatalf("Expected timeout handler to be registered, found %v", err)
+	}
+	return states
+}
+
+func TestScheduleTimeoutShouldRunHandlerWithSession(t *testing.T) {
+	// Arrange
+	h, app, clock := newTimeoutHandlerContext(t, "ask_name")
+	states := recordTimeout(t, app)
+
+	if err := h.ScheduleTimeout(10*time.Minute, "nudge"); err != nil {
+		t.Fatalf("Expected timeout to be scheduled, found %v", err)
+	}
+
+	// Act
+	clock.Advance(10 * time.Minute)
+
+	// Assert
+	select {
+	case state := <-states:
+		if state != "ask_name" {
+			t.Errorf("Expected session in state ask_name, found %q", state)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected timeout handler to run")
+	}
+}
+
+func TestTimeoutMiddlewareShouldCancelTimeoutOnUpdate(t *testing.T) {
+	// Arrange
+	h, app, clock := newTimeoutHandlerContext(t, "ask_name")
+	states := recordTimeout(t, app)
+	h.ScheduleTimeout(10*time.Minute, "nudge")
+
+	var handled bool
+	mw := app.Jobs.TimeoutMiddleware()
+
+	// Act
+	mw(tgbotapp.NewBotContext(t.Context(), app, newChatUpdate(1, "Bob")), func(*tgbotapp.BotContext) { handled = true })
+	clock.Advance(time.Hour)
+
+	// Assert
+	if !handled {
+		t.Error("Expected update to be handled")
+	}
+	if jobs := app.Jobs.Jobs(); len(jobs) != 0 {
+		t.Errorf("Expected timeout to be canceled, found %+v", jobs)
+	}
+	select {
+	case <-states:
+		t.Error("Expected canceled timeout not to run")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestScheduleTimeoutShouldSkipWhenStateChanged(t *testing.T) {
+	// Arrange
+	h, app, clock := newTimeoutHandlerContext(t, "ask_name")
+	states := recordTimeout(t, app)
+	h.ScheduleTimeout(10*time.Minute, "nudge")
+
+	h.Session.SetState("done")
+	app.SessionManager.Set(1, h.Session)
+
+	// Act
+	clock.Advance(10 * time.Minute)
+
+	// Assert
+	select {
+	case state := <-states:
+		t.Errorf("Expected timeout to be skipped, found handler run in state %q", state)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestCancelTimeoutShouldRequireJobScheduler(t *testing.T) {
+	// Arrange
+	h, _ := newHandlerContext(t)
+
+	// Act
+	err := h.CancelTimeout()
+
+	// Assert
+	if !errors.Is(err, tgbotapp.ErrNoJobScheduler) {
+		t.Errorf("Expected ErrNoJobScheduler, found %v", err)
+	}
+}
+
+func TestTimeoutShouldUseSessionOptionsOfTheApplication(t *testing.T) {
+	// Arrange
+	f := testutil.NewFakeBotAPI(t)
+	clock := testutil.NewFakeClock(jobsEpoch)
+	app := tgbotapp.New(f.NewBotAPI(t), tgbotapp.WithJobs(tgbotapp.WithClock(clock)))
+	app.Logger = slog.Default()
+	app.Router = tgbotapp.NewRouteTable()
+	app.SessionManager = tgbotapp.NewStoreManager(&failingStore{tgbotapp.NewMemoryStore(), 1}, nil)
+
+	loadErrs := make(chan error, 1)
+	app.UseSession(
+		tgbotapp.WithLoadErrorPolicy(tgbotapp.LoadErrorFail),
+		tgbotapp.WithLoadErrorHandler(func(ctx *tgbotapp.BotContext, err error) {
+			loadErrs <- err
+		}),
+	)
+	states := recordTimeout(t, app)
+	runJobs(t, app.Jobs)
+
+	h := tgbotapp.NewHandlerContext(tgbotapp.NewBotContext(t.Context(), app, newChatUpdate(1, "")), "test")
+	if err := h.ScheduleTimeout(time.Minute, "nudge"); err != nil {
+		t.Fatalf("Expected timeout to be scheduled, found %v", err)
+	}
+
+	// Act
+	clock.Advance(time.Minute)
+
+	// Assert
+	select {
+	case err := <-loadErrs:
+		if err == nil {
+			t.Error(expectsError)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected load error handler to be called")
+	}
+	select {
+	case state := <-states:
+		t.Errorf("Expected timeout handler not to run, found state %q", state)
+	case <-time.After(20 * time.Millisecond):
+	}
+}